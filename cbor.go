@@ -0,0 +1,69 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBORCodec is the Codec of CBOR (application/cbor).
+//
+// The streamed inputs and parts are CBOR sequences.
+// Decoding falls back to the lenient weakDecode, as JSON does.
+type CBORCodec struct{}
+
+var (
+	_ Codec = CBORCodec{}
+
+	cborEncMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	// unknown fields are tried with weakDecode
+	cborDecMode, _ = cbor.DecOptions{ExtraReturnErrors: cbor.ExtraDecErrorUnknownField}.DecMode()
+)
+
+func (CBORCodec) ContentType() string { return "application/cbor" }
+func (CBORCodec) NewDecoder(r io.Reader) Decoder {
+	return cborDecoder{dec: cborDecMode.NewDecoder(r)}
+}
+func (CBORCodec) NewEncoder(w io.Writer) Encoder { return cborEncoder{enc: cborEncMode.NewEncoder(w)} }
+
+type cborDecoder struct {
+	dec *cbor.Decoder
+}
+
+func (d cborDecoder) Decode(v any) error {
+	var raw cbor.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return err
+	}
+	err := cborDecMode.Unmarshal(raw, v)
+	if err == nil {
+		return nil
+	}
+	origErr := err
+	m := mapPool.Get().(map[string]any)
+	defer func() {
+		for k := range m {
+			delete(m, k)
+		}
+		mapPool.Put(m)
+	}()
+	if err = cborDecMode.Unmarshal(raw, &m); err != nil {
+		return fmt.Errorf("decode: %w (was: %+v)", err, origErr)
+	}
+	if err = weakDecode(m, v, false); err != nil {
+		return fmt.Errorf("%w (was: %+v)", err, origErr)
+	}
+	return nil
+}
+
+type cborEncoder struct {
+	enc *cbor.Encoder
+}
+
+func (e cborEncoder) Encode(v any) error          { return e.enc.Encode(v) }
+func (e cborEncoder) EncodeError(err error) error { return e.enc.Encode(newErrorValue(err)) }
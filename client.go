@@ -1,4 +1,4 @@
-// Copyright 2017, 2025 Tamás Gulácsi
+// Copyright 2017, 2026 Tamás Gulácsi
 //
 // SPDX-License-Identifier: Apache-2.0
 
@@ -7,8 +7,11 @@ package grpcer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"sync"
 
 	"github.com/UNO-SOFT/w3ctrace"
 	"github.com/UNO-SOFT/w3ctrace/gtrace"
@@ -18,6 +21,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // Receiver is an interface for Recv()-ing streamed responses from the server.
@@ -25,6 +29,19 @@ type Receiver interface {
 	Recv() (any, error)
 }
 
+// Sender is an interface for Send-ing streamed requests to the server.
+type Sender interface {
+	Send(any) error
+	// CloseSend closes the sending side of the stream.
+	CloseSend() error
+}
+
+// Stream is an interface for client-streaming and bidirectional calls.
+type Stream interface {
+	Sender
+	Receiver
+}
+
 // Client is the client interface for calling a gRPC server.
 type Client interface {
 	// List the available names
@@ -35,6 +52,103 @@ type Client interface {
 	Call(name string, ctx context.Context, input any, opts ...grpc.CallOption) (Receiver, error)
 }
 
+// StreamClient is a Client which can call client-streaming and bidirectional functions, too.
+type StreamClient interface {
+	Client
+	// CallStream opens a Stream for the named function.
+	CallStream(name string, ctx context.Context, opts ...grpc.CallOption) (Stream, error)
+}
+
+// MethodInfo is an optional interface of a Client (implemented by the clients generated by protoc-gen-grpcer),
+// describing its methods.
+type MethodInfo interface {
+	// Tags returns the tags of the named method.
+	Tags(name string) []string
+	// FullMethod returns the full gRPC method name ("/package.Service/Method").
+	FullMethod(name string) string
+	// Streaming reports whether the client and the server streams.
+	Streaming(name string) (clientStreaming, serverStreaming bool)
+	// Descriptors returns the descriptors of the input and output messages.
+	Descriptors(name string) (input, output protoreflect.MessageDescriptor)
+	// Comments returns the leading comments of the method in the proto file.
+	Comments(name string) string
+}
+
+// CallStream opens a Stream for the named function.
+//
+// If c is not a StreamClient, then the returned Stream accepts only one input,
+// and calls the function with it on the first Recv.
+func CallStream(c Client, name string, ctx context.Context, opts ...grpc.CallOption) (Stream, error) {
+	if sc, ok := c.(StreamClient); ok {
+		return sc.CallStream(name, ctx, opts...)
+	}
+	return NewUnaryStream(ctx, func(input any) (Receiver, error) {
+		return c.Call(name, ctx, input, opts...)
+	}), nil
+}
+
+var errSendClosed = errors.New("send closed")
+
+// NewUnaryStream returns a Stream for a function which accepts only one input (unary or server-streaming).
+//
+// The call is executed with the sent input on the first Recv,
+// which blocks till Send or CloseSend is called, or ctx is done.
+func NewUnaryStream(ctx context.Context, call func(input any) (Receiver, error)) Stream {
+	return &unaryStream{ctx: ctx, call: call, inputs: make(chan any, 1)}
+}
+
+type unaryStream struct {
+	ctx    context.Context
+	call   func(any) (Receiver, error)
+	inputs chan any
+	recv   Receiver
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *unaryStream) Send(input any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("only one input is allowed: %w", errSendClosed)
+	}
+	s.inputs <- input
+	s.closed = true
+	close(s.inputs)
+	return nil
+}
+
+func (s *unaryStream) CloseSend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.inputs)
+	}
+	return nil
+}
+
+func (s *unaryStream) Recv() (any, error) {
+	if s.recv == nil {
+		var input any
+		var ok bool
+		select {
+		case <-s.ctx.Done():
+			return nil, context.Cause(s.ctx)
+		case input, ok = <-s.inputs:
+		}
+		if !ok {
+			return nil, fmt.Errorf("no input has been sent: %w", io.ErrUnexpectedEOF)
+		}
+		recv, err := s.call(input)
+		if err != nil {
+			return nil, err
+		}
+		s.recv = recv
+	}
+	return s.recv.Recv()
+}
+
 // DialConfig contains the configuration variables.
 type DialConfig struct {
 	*slog.Logger
@@ -0,0 +1,68 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestUnaryStream(t *testing.T) {
+	var calls int
+	s := NewUnaryStream(context.Background(), func(input any) (Receiver, error) {
+		calls++
+		return &receiver{parts: []any{input, input}}, nil
+	})
+	done := make(chan []any)
+	go func() {
+		var parts []any
+		for {
+			part, err := s.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					t.Error(err)
+				}
+				break
+			}
+			parts = append(parts, part)
+		}
+		done <- parts
+	}()
+	if err := s.Send("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send("b"); err == nil {
+		t.Error("second Send succeeded")
+	}
+	if err := s.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if parts := <-done; len(parts) != 2 || parts[0] != "a" || calls != 1 {
+		t.Errorf("got %v (%d calls)", parts, calls)
+	}
+
+	s = NewUnaryStream(context.Background(), func(input any) (Receiver, error) {
+		t.Error("called without input")
+		return nil, nil
+	})
+	s.CloseSend()
+	if _, err := s.Recv(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %+v, wanted ErrUnexpectedEOF", err)
+	}
+
+	// neither Send nor CloseSend is called
+	ctx, cancel := context.WithCancelCause(context.Background())
+	s = NewUnaryStream(ctx, func(input any) (Receiver, error) {
+		t.Error("called without input")
+		return nil, nil
+	})
+	failed := errors.New("failed")
+	cancel(failed)
+	if _, err := s.Recv(); !errors.Is(err, failed) {
+		t.Errorf("got %+v, wanted %+v", err, failed)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec decodes the inputs from, and encodes the output parts to a media type.
+type Codec interface {
+	// ContentType is the media type of the encoded parts.
+	ContentType() string
+	// NewDecoder returns a Decoder of the inputs read from r.
+	NewDecoder(r io.Reader) Decoder
+	// NewEncoder returns an Encoder of the parts written to w.
+	NewEncoder(w io.Writer) Encoder
+}
+
+// Decoder decodes the inputs one after the other, returning io.EOF after the last.
+type Decoder interface {
+	Decode(v any) error
+}
+
+// Encoder encodes the parts one after the other.
+//
+// An Encoder implementing io.Closer is closed after the last part.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// MethodDecoder is a Decoder which decodes the name of the called method from the request, too (as XML-RPC).
+type MethodDecoder interface {
+	Decoder
+	Method() (string, error)
+}
+
+// ErrorEncoder is an Encoder with its own error format (as XML-RPC faults).
+//
+// Other Encoders encode the ErrorBody of the error.
+type ErrorEncoder interface {
+	Encoder
+	EncodeError(err error) error
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		"application/json":        JSONCodec{},
+		"application/x-ndjson":    JSONCodec{},
+		"text/xml":                XMLRPCCodec{},
+		"application/cbor":        CBORCodec{},
+		"application/cbor-seq":    CBORCodec{},
+		"application/msgpack":     MsgpackCodec{},
+		"application/x-msgpack":   MsgpackCodec{},
+		"application/vnd.msgpack": MsgpackCodec{},
+		"application/x-protobuf":  ProtobufCodec{},
+		"application/protobuf":    ProtobufCodec{},
+	}
+)
+
+// RegisterCodec registers the Codec for the media type, replacing the previous one.
+func RegisterCodec(mediaType string, codec Codec) {
+	codecsMu.Lock()
+	codecs[strings.ToLower(mediaType)] = codec
+	codecsMu.Unlock()
+}
+
+// LookupCodec returns the Codec registered for the media type (its parameters are ignored), or nil.
+func LookupCodec(mediaType string) Codec {
+	if mt, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = mt
+	}
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	return codecs[strings.ToLower(mediaType)]
+}
+
+// acceptCodec returns the Codec of the most preferred media type of the Accept header
+// that is found with lookup, or nil.
+func acceptCodec(accept string, lookup func(string) Codec) Codec {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, s := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(s))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mt, q: q})
+	}
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		if a.q > b.q {
+			return -1
+		} else if a.q < b.q {
+			return 1
+		}
+		return 0
+	})
+	for _, r := range ranges {
+		if strings.HasSuffix(r.mediaType, "/*") {
+			return nil
+		}
+		if c := lookup(r.mediaType); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// encodeError encodes the error with the Encoder: as an ErrorBody, unless it is an ErrorEncoder.
+func encodeError(enc Encoder, err error) error {
+	if ee, ok := enc.(ErrorEncoder); ok {
+		return ee.EncodeError(err)
+	}
+	return enc.Encode(NewErrorBody(err))
+}
+
+// closeEncoder closes the Encoder if it is an io.Closer.
+func closeEncoder(enc Encoder) error {
+	if c, ok := enc.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// codecError writes the error, encoded with the codec, with the status code (500 if 0).
+func codecError(w http.ResponseWriter, codec Codec, err error, code int) {
+	w.Header().Set("Content-Type", codec.ContentType())
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	w.WriteHeader(code)
+	enc := codec.NewEncoder(w)
+	encodeError(enc, err)
+	closeEncoder(enc)
+}
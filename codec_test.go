@@ -0,0 +1,175 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/grpcer"
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+type gobCodec struct{}
+
+func (gobCodec) ContentType() string                   { return "application/x-gob" }
+func (gobCodec) NewDecoder(r io.Reader) grpcer.Decoder { return gob.NewDecoder(r) }
+func (gobCodec) NewEncoder(w io.Writer) grpcer.Encoder { return gob.NewEncoder(w) }
+
+func TestCodec(t *testing.T) {
+	grpcer.RegisterCodec("application/x-gob", gobCodec{})
+	h := grpcer.JSONHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()}
+
+	gobBody := func(inputs ...testInput) io.Reader {
+		var buf bytes.Buffer
+		enc := gob.NewEncoder(&buf)
+		for _, inp := range inputs {
+			if err := enc.Encode(inp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &buf
+	}
+	for tN, tC := range map[string]struct {
+		Body        io.Reader
+		ContentType string
+		Name        string
+		Want        []testOutput
+	}{
+		"gob":      {Name: "Count", ContentType: "application/x-gob", Body: gobBody(testInput{A: 3}), Want: []testOutput{{Count: 1}, {Sum: 1, Count: 2}, {Sum: 2, Count: 3}}},
+		"streamed": {Name: "Sum", ContentType: "application/x-gob", Body: gobBody(testInput{A: 1}, testInput{A: 2}, testInput{A: 3}), Want: []testOutput{{Sum: 6, Count: 3}}},
+		"accept":   {Name: "Count", ContentType: "application/json", Body: strings.NewReader(`{"a":2}`), Want: []testOutput{{Count: 1}, {Sum: 1, Count: 2}}},
+	} {
+		r := httptest.NewRequest("POST", "/"+tC.Name, tC.Body)
+		r.Header.Set("Content-Type", tC.ContentType)
+		r.Header.Set("Accept", "text/html;q=0.9, application/x-gob, */*;q=0.1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 200 || w.Header().Get("Content-Type") != "application/x-gob" {
+			t.Fatalf("%s: got %d %q: %s", tN, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		dec := gob.NewDecoder(w.Body)
+		var got []testOutput
+		for {
+			var out testOutput
+			if err := dec.Decode(&out); err != nil {
+				if !errors.Is(err, io.EOF) {
+					t.Fatalf("%s: %+v", tN, err)
+				}
+				break
+			}
+			got = append(got, out)
+		}
+		if len(got) != len(tC.Want) {
+			t.Fatalf("%s: got %+v, wanted %+v", tN, got, tC.Want)
+		}
+		for i, g := range got {
+			if g != tC.Want[i] {
+				t.Errorf("%s: %d. got %+v, wanted %+v", tN, i, g, tC.Want[i])
+			}
+		}
+	}
+}
+
+func TestXMLRPCHandlerAccept(t *testing.T) {
+	h := grpcer.XMLRPCHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()}
+	const body = `<?xml version="1.0"?>
+<methodCall><methodName>Count</methodName><params><param><value><struct>
+<member><name>a</name><value><int>2</int></value></member>
+</struct></value></param></params></methodCall>`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "<methodResponse>") {
+		t.Fatalf("got %d: %s", w.Code, w.Body.String())
+	}
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 200 || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got %d %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	dec := json.NewDecoder(w.Body)
+	var got []testOutput
+	for dec.More() {
+		var out testOutput
+		if err := dec.Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, out)
+	}
+	if len(got) != 2 || got[1] != (testOutput{Sum: 1, Count: 2}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestBinaryCodecs(t *testing.T) {
+	h := grpcer.JSONHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()}
+	type errorValue struct {
+		Error, Code, Message string
+	}
+	for _, codec := range []grpcer.Codec{grpcer.CBORCodec{}, grpcer.MsgpackCodec{}} {
+		for tN, tC := range map[string]struct {
+			Name   string
+			Inputs []map[string]any
+			Want   []testOutput
+			Code   string
+		}{
+			"lenient":  {Name: "Count", Inputs: []map[string]any{{"A": 2}}, Want: []testOutput{{Count: 1}, {Sum: 1, Count: 2}}},
+			"streamed": {Name: "Sum", Inputs: []map[string]any{{"a": 1}, {"a": "2"}}, Want: []testOutput{{Sum: 3, Count: 2}}},
+			"error":    {Name: "Fail", Inputs: []map[string]any{{"a": 1}}, Want: []testOutput{{Count: 1}}, Code: "Unavailable"},
+		} {
+			tN = codec.ContentType() + "/" + tN
+			var buf bytes.Buffer
+			enc := codec.NewEncoder(&buf)
+			for _, inp := range tC.Inputs {
+				if err := enc.Encode(inp); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r := httptest.NewRequest("POST", "/"+tC.Name, &buf)
+			r.Header.Set("Content-Type", codec.ContentType())
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != 200 || w.Header().Get("Content-Type") != codec.ContentType() {
+				t.Fatalf("%s: got %d %q: %q", tN, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+			}
+			dec := codec.NewDecoder(w.Body)
+			var got []testOutput
+			for range tC.Want {
+				var out testOutput
+				if err := dec.Decode(&out); err != nil {
+					t.Fatalf("%s: %+v", tN, err)
+				}
+				got = append(got, out)
+			}
+			if tC.Code != "" {
+				var ev errorValue
+				if err := dec.Decode(&ev); err != nil {
+					t.Fatalf("%s: %+v", tN, err)
+				}
+				if ev.Code != tC.Code {
+					t.Errorf("%s: got %+v, wanted code %q", tN, ev, tC.Code)
+				}
+			}
+			if err := dec.Decode(new(testOutput)); !errors.Is(err, io.EOF) {
+				t.Errorf("%s: got %+v, wanted EOF", tN, err)
+			}
+			for i, g := range got {
+				if g != tC.Want[i] {
+					t.Errorf("%s: %d. got %+v, wanted %+v", tN, i, g, tC.Want[i])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	// registers the standard error details (BadRequest, RetryInfo, ErrorInfo...) for protojson, too
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorBody is the body of the JSON error responses.
+//
+// Error is the full error string, Code is the name of the gRPC status code,
+// Message is the status message, and Details are the status details rendered with protojson.
+type ErrorBody struct {
+	Error   string
+	Code    string            `json:",omitempty"`
+	Message string            `json:",omitempty"`
+	Details []json.RawMessage `json:",omitempty"`
+}
+
+// NewErrorBody returns the ErrorBody for the error.
+func NewErrorBody(err error) ErrorBody {
+	if err == nil {
+		return ErrorBody{}
+	}
+	eb := ErrorBody{Error: err.Error()}
+	if st, ok := grpcStatus(err); ok {
+		eb.Code, eb.Message = st.Code().String(), st.Message()
+		eb.Details = statusDetails(st)
+	}
+	return eb
+}
+
+// errorValue is the ErrorBody with its details decoded, for the non-JSON encodings.
+type errorValue struct {
+	Error   string
+	Code    string `json:",omitempty"`
+	Message string `json:",omitempty"`
+	Details []any  `json:",omitempty"`
+}
+
+func newErrorValue(err error) errorValue {
+	eb := NewErrorBody(err)
+	ev := errorValue{Error: eb.Error, Code: eb.Code, Message: eb.Message}
+	for _, d := range eb.Details {
+		var v any
+		if json.Unmarshal(d, &v) == nil {
+			ev.Details = append(ev.Details, v)
+		}
+	}
+	return ev
+}
+
+// grpcStatus returns the gRPC status of the error, unwrapping it as necessary.
+func grpcStatus(err error) (*status.Status, bool) {
+	var gs interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &gs) {
+		if st := gs.GRPCStatus(); st != nil {
+			return st, true
+		}
+	}
+	return status.New(codes.Unknown, err.Error()), false
+}
+
+// toStatus returns the gRPC status of the error, with DeadlineExceeded, Canceled and NotFound codes
+// for the context errors and ErrNotFound, and OK for nil.
+func toStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	st, ok := grpcStatus(err)
+	if ok {
+		return st
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, err.Error())
+	case errors.Is(err, ErrNotFound):
+		return status.New(codes.NotFound, err.Error())
+	}
+	return st
+}
+
+// statusDetails renders the details of the status with protojson.
+//
+// Details with unknown type are rendered as {"@type":..., "value": base64}.
+func statusDetails(st *status.Status) []json.RawMessage {
+	anys := st.Proto().GetDetails()
+	if len(anys) == 0 {
+		return nil
+	}
+	details := make([]json.RawMessage, 0, len(anys))
+	for _, a := range anys {
+		b, err := protojson.Marshal(a)
+		if err != nil {
+			if b, err = json.Marshal(struct {
+				Type  string `json:"@type"`
+				Value []byte `json:"value"`
+			}{Type: a.GetTypeUrl(), Value: a.GetValue()}); err != nil {
+				continue
+			}
+		}
+		details = append(details, json.RawMessage(b))
+	}
+	return details
+}
+
+// StatusCodeFromError returns the HTTP status code for the error,
+// using the canonical mapping of its gRPC status code.
+func StatusCodeFromError(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	st, ok := grpcStatus(err)
+	if !ok {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return http.StatusGatewayTimeout
+		case errors.Is(err, context.Canceled):
+			return 499 // Client Closed Request
+		case errors.Is(err, ErrNotFound):
+			return http.StatusNotFound
+		}
+	}
+	switch st.Code() {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.Unknown:
+		if desc := st.Message(); desc == "bad username or password" {
+			return http.StatusUnauthorized
+		}
+		return http.StatusInternalServerError
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// retryDelay returns the delay of the RetryInfo detail of the error's status, or 0.
+func retryDelay(err error) time.Duration {
+	st, ok := grpcStatus(err)
+	if !ok {
+		return 0
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration()
+		}
+	}
+	return 0
+}
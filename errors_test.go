@@ -0,0 +1,122 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestErrorBody(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "bad input").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "a", Description: "too big"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fmt.Errorf("Call x: %w", st.Err())
+	eb := NewErrorBody(err)
+	if eb.Code != "InvalidArgument" || eb.Message != "bad input" || eb.Error != err.Error() {
+		t.Errorf("got %+v", eb)
+	}
+	if len(eb.Details) != 1 || !strings.Contains(string(eb.Details[0]), `"type.googleapis.com/google.rpc.BadRequest"`) ||
+		!strings.Contains(string(eb.Details[0]), `"too big"`) {
+		t.Errorf("got details %s", eb.Details)
+	}
+
+	w := httptest.NewRecorder()
+	faultError(w, XMLRPCCodec{}, err)
+	var resp struct {
+		Members []struct {
+			Name    string   `xml:"name"`
+			String  string   `xml:"value>string"`
+			Strings []string `xml:"value>array>data>value>string"`
+			Int     int      `xml:"value>int"`
+		} `xml:"fault>value>struct>member"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s: %+v", w.Body.String(), err)
+	}
+	got := make(map[string]string, len(resp.Members))
+	for _, m := range resp.Members {
+		switch m.Name {
+		case "faultCode":
+			got[m.Name] = strconv.Itoa(m.Int)
+		case "details":
+			got[m.Name] = strings.Join(m.Strings, ",")
+		default:
+			got[m.Name] = m.String
+		}
+	}
+	if got["faultCode"] != "3" || got["faultString"] != eb.Error || got["grpcCode"] != "InvalidArgument" ||
+		got["details"] != string(eb.Details[0]) {
+		t.Errorf("got %q", got)
+	}
+
+	if eb = NewErrorBody(errors.New("plain")); eb.Code != "" || eb.Error != "plain" {
+		t.Errorf("got %+v", eb)
+	}
+}
+
+func TestStatusCodeFromError(t *testing.T) {
+	for err, want := range map[error]int{
+		status.Error(codes.NotFound, "x"): 404,
+		fmt.Errorf("a: %w", fmt.Errorf("b: %w", status.Error(codes.PermissionDenied, "x"))): 403,
+		status.Error(codes.Unauthenticated, "x"):                                            401,
+		status.Error(codes.Unknown, "bad username or password"):                             401,
+		status.Error(codes.InvalidArgument, "x"):                                            400,
+		status.Error(codes.DeadlineExceeded, "x"):                                           504,
+		status.Error(codes.Unavailable, "x"):                                                503,
+		status.Error(codes.ResourceExhausted, "x"):                                          429,
+		status.Error(codes.Internal, "x"):                                                   500,
+		fmt.Errorf("x: %w", context.DeadlineExceeded):                                       504,
+		errors.New("x"): 500,
+	} {
+		if got := StatusCodeFromError(err); got != want {
+			t.Errorf("%v: got %d, wanted %d", err, got, want)
+		}
+	}
+
+	st, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tC := range []struct {
+		Handler    JSONHandler
+		Err        error
+		Code       int
+		RetryAfter string
+	}{
+		{Err: st.Err(), Code: 429, RetryAfter: "2"},
+		{Err: status.Error(codes.ResourceExhausted, "x"), Code: 429, RetryAfter: "1"},
+		{Err: status.Error(codes.Unavailable, "x"), Code: 503},
+		{Err: status.Error(codes.NotFound, "x"), Code: 410,
+			Handler: JSONHandler{StatusCode: func(err error) int {
+				if status.Code(err) == codes.NotFound {
+					return http.StatusGone
+				}
+				return 0
+			}}},
+	} {
+		w := httptest.NewRecorder()
+		tC.Handler.httpError(w, JSONCodec{}, fmt.Errorf("Call: %w", tC.Err))
+		if w.Code != tC.Code || w.Header().Get("Retry-After") != tC.RetryAfter {
+			t.Errorf("%v: got %d %q, wanted %d %q", tC.Err, w.Code, w.Header().Get("Retry-After"), tC.Code, tC.RetryAfter)
+		}
+	}
+}
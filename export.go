@@ -0,0 +1,361 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// table collects the rows of the parts, flattened into columns.
+//
+// The rows are the elements of the primary (first) slice field of the parts,
+// preceded by the non-slice fields of their part; parts without slice fields are one row each.
+// Nested objects are flattened into columns named with the dot-joined path of the keys.
+type table struct {
+	index   map[string]int
+	columns []string
+	primary string
+}
+
+// rows appends the rows of the part to rows.
+func (t *table) rows(rows [][]json.RawMessage, codec JSONCodec, part any) ([][]json.RawMessage, error) {
+	slice, notSlice, err := codec.fields(part)
+	if err != nil {
+		return rows, err
+	}
+	var base []json.RawMessage
+	for _, f := range notSlice {
+		raw, err := fieldJSON(f.Value)
+		if err != nil {
+			return rows, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if raw != nil {
+			base = t.flatten(base, f.TagName, raw)
+		}
+	}
+	if t.primary == "" && len(slice) != 0 {
+		t.primary = slice[0].Name
+	}
+	if t.primary == "" {
+		return append(rows, base), nil
+	}
+	for _, f := range slice {
+		if f.Name != t.primary {
+			continue
+		}
+		raw, err := fieldJSON(f.Value)
+		if err != nil {
+			return rows, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		var elems []json.RawMessage
+		if err = json.Unmarshal(raw, &elems); err != nil {
+			return rows, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		for _, elem := range elems {
+			rows = append(rows, t.flatten(slices.Clip(base), "", elem))
+		}
+		break
+	}
+	return rows, nil
+}
+
+// flatten the JSON value into the row, under the column named prefix.
+func (t *table) flatten(row []json.RawMessage, prefix string, raw json.RawMessage) []json.RawMessage {
+	if raw = bytes.TrimSpace(raw); len(raw) != 0 && raw[0] == '{' {
+		if keys, values, err := objectFields(raw); err == nil {
+			for i, k := range keys {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				row = t.flatten(row, k, values[i])
+			}
+			return row
+		}
+	}
+	if prefix == "" {
+		prefix = "value"
+	}
+	i, ok := t.index[prefix]
+	if !ok {
+		i = len(t.columns)
+		t.columns = append(t.columns, prefix)
+		t.index[prefix] = i
+	}
+	for len(row) <= i {
+		row = append(row, nil)
+	}
+	row[i] = raw
+	return row
+}
+
+// fieldJSON returns the JSON encoding of the field's value.
+func fieldJSON(v any) (json.RawMessage, error) {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return json.Marshal(v)
+}
+
+// objectFields returns the keys and values of the JSON object, in order.
+func objectFields(raw json.RawMessage) ([]string, []json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil {
+		return nil, nil, err
+	} else if tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("wanted {, got %v", tok)
+	}
+	var keys []string
+	var values []json.RawMessage
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var v json.RawMessage
+		if err = dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, tok.(string))
+		values = append(values, v)
+	}
+	return keys, values, nil
+}
+
+// cellText returns the text of the JSON value, and its kind: 's'tring, 'n'umber, 'b'ool or 0 for null.
+func cellText(raw json.RawMessage) (string, byte) {
+	if len(raw) == 0 {
+		return "", 0
+	}
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s, 's'
+		}
+	case 'n':
+		return "", 0
+	case 't', 'f':
+		return string(raw), 'b'
+	case '{', '[':
+	default:
+		return string(raw), 'n'
+	}
+	return string(raw), 's'
+}
+
+// serveTable writes the received parts as a CSV or an XLSX table.
+//
+// The rows are buffered in a SpillBuffer, as the columns are known only after the last part.
+func (h JSONHandler) serveTable(w http.ResponseWriter, format, name string, part any, recv Receiver, logger *slog.Logger) {
+	fh := h.mergeConfig().newBuffer("table-*.json.zst")
+	defer fh.Close()
+	enc := json.NewEncoder(fh)
+	t := table{index: make(map[string]int)}
+	var rows [][]json.RawMessage
+	for part, err := range prepend(part, All(recv)) {
+		if err != nil {
+			logger.Error("recv", "error", err)
+			h.httpError(w, h.JSON, fmt.Errorf("recv: %w", err))
+			return
+		}
+		if rows, err = t.rows(rows[:0], h.JSON, part); err != nil {
+			logger.Error("rows", "error", err)
+			jsonError(w, err, http.StatusInternalServerError)
+			return
+		}
+		for _, row := range rows {
+			if err = enc.Encode(row); err != nil {
+				logger.Error("encode", "error", err)
+				jsonError(w, err, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	rc, err := fh.GetReader()
+	if err != nil {
+		logger.Error("GetReader", "error", err)
+		jsonError(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+	dec := json.NewDecoder(rc)
+	next := func() ([]json.RawMessage, error) {
+		var row []json.RawMessage
+		err := dec.Decode(&row)
+		return row, err
+	}
+
+	if format == "xlsx" {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".xlsx"}))
+		w.WriteHeader(200)
+		err = writeXLSX(w, name, t.columns, next)
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".csv"}))
+		w.WriteHeader(200)
+		err = writeCSV(w, t.columns, next)
+	}
+	if err != nil {
+		logger.Error("write", "format", format, "error", err)
+	}
+}
+
+// writeCSV writes the header and the rows returned by next until io.EOF as CSV.
+func writeCSV(w io.Writer, columns []string, next func() ([]json.RawMessage, error)) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for {
+		row, err := next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		for i := range record {
+			record[i] = ""
+			if i < len(row) {
+				record[i], _ = cellText(row[i])
+			}
+		}
+		if err = cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeXLSX writes the header and the rows returned by next until io.EOF as a one-sheet XLSX workbook.
+func writeXLSX(w io.Writer, sheetName string, columns []string, next func() ([]json.RawMessage, error)) error {
+	sheetName = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, sheetName)
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	} else if rs := []rune(sheetName); len(rs) > 31 {
+		sheetName = string(rs[:31])
+	}
+	zw := zip.NewWriter(w)
+	for _, f := range []struct{ Name, Content string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + xmlEscape(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+	} {
+		fw, err := zw.Create(f.Name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(fw, f.Content); err != nil {
+			return err
+		}
+	}
+
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(fw)
+	bw.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	writeRow := func(r int, cells []string, kinds []byte) {
+		rs := strconv.Itoa(r)
+		bw.WriteString(`<row r="` + rs + `">`)
+		for i, s := range cells {
+			ref := columnName(i) + rs
+			switch kinds[i] {
+			case 0:
+				continue
+			case 'n':
+				bw.WriteString(`<c r="` + ref + `"><v>` + s + `</v></c>`)
+			case 'b':
+				v := "0"
+				if s == "true" {
+					v = "1"
+				}
+				bw.WriteString(`<c r="` + ref + `" t="b"><v>` + v + `</v></c>`)
+			default:
+				bw.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+				xml.EscapeText(bw, []byte(s))
+				bw.WriteString(`</t></is></c>`)
+			}
+		}
+		bw.WriteString(`</row>`)
+	}
+	kinds := make([]byte, len(columns))
+	for i := range kinds {
+		kinds[i] = 's'
+	}
+	writeRow(1, columns, kinds)
+	cells := make([]string, len(columns))
+	for r := 2; ; r++ {
+		row, err := next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		for i := range cells {
+			cells[i], kinds[i] = "", 0
+			if i < len(row) {
+				cells[i], kinds[i] = cellText(row[i])
+			}
+		}
+		writeRow(r, cells, kinds)
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// columnName returns the spreadsheet name of the i-th (0-based) column: A, B, ..., Z, AA, AB...
+func columnName(i int) string {
+	var b []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		b = append(b, byte('A'+(i-1)%26))
+	}
+	slices.Reverse(b)
+	return string(b)
+}
+
+func xmlEscape(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
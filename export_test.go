@@ -0,0 +1,148 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServeTable(t *testing.T) {
+	type row struct {
+		Name string `json:"name"`
+		Sub  struct {
+			A int  `json:"a"`
+			B bool `json:"b,omitempty"`
+		} `json:"sub"`
+	}
+	type part struct {
+		Title string `json:"title"`
+		Rows  []row  `json:"rows"`
+		Other []int  `json:"other"`
+	}
+	r1, r2, r3 := row{Name: "x"}, row{Name: "y, z"}, row{Name: "w"}
+	r1.Sub.A, r2.Sub.A, r2.Sub.B = 1, 2, true
+	parts := []any{
+		part{Title: "first", Rows: []row{r1, r2}, Other: []int{9}},
+		part{Title: "second", Rows: []row{r3}},
+	}
+
+	for tN, tC := range map[string]struct {
+		Err   error
+		Input []any
+		Want  string
+		Code  int
+	}{
+		"noSlice": {
+			Input: toIntf([]struct {
+				B string
+				A int
+			}{{A: 1, B: "x"}, {A: 2}}),
+			Want: "B,A\nx,1\n,2\n",
+		},
+		"slice": {
+			Input: parts,
+			Want:  "title,name,sub.a,sub.b\nfirst,x,1,\nfirst,\"y, z\",2,true\nsecond,w,0,\n",
+		},
+		"recvError": {
+			Input: parts,
+			Err:   status.Error(codes.Unavailable, "failed"),
+			Code:  503,
+		},
+	} {
+		recv := &receiver{parts: tC.Input, err: tC.Err}
+		first, _ := recv.Recv()
+		w := httptest.NewRecorder()
+		JSONHandler{}.serveTable(w, "csv", "Test", first, recv, zlog.NewT(t).SLog())
+		if tC.Code == 0 {
+			tC.Code = 200
+		}
+		if w.Code != tC.Code {
+			t.Errorf("%s: got code %d, wanted %d", tN, w.Code, tC.Code)
+		}
+		if tC.Want != "" && w.Body.String() != tC.Want {
+			t.Errorf("%s: got\n%s\nwanted\n%s", tN, w.Body.String(), tC.Want)
+		}
+	}
+
+	recv := &receiver{parts: parts}
+	first, _ := recv.Recv()
+	w := httptest.NewRecorder()
+	JSONHandler{}.serveTable(w, "xlsx", "Test", first, recv, zlog.NewT(t).SLog())
+	if got := w.Header().Get("Content-Type"); got != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Errorf("got content-type %q", got)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(b)
+	}
+	for _, want := range []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">title</t></is></c>`,
+		`<c r="B3" t="inlineStr"><is><t xml:space="preserve">y, z</t></is></c><c r="C3"><v>2</v></c><c r="D3" t="b"><v>1</v></c></row>`,
+		`<row r="4">`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("%q not found in %s", want, sheet)
+		}
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	var buf bytes.Buffer
+	name := strings.Repeat("á", 40)
+	if err := writeXLSX(&buf, name, []string{"a"}, func() ([]json.RawMessage, error) { return nil, io.EOF }); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := zr.Open("xl/workbook.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.Valid(b) || !strings.Contains(string(b), `<sheet name="`+strings.Repeat("á", 31)+`"`) {
+		t.Errorf("got %s", b)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("%d: got %q, wanted %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,519 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/klauspost/compress/gzip"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// GatewayHandler serves the Client's functions with the gRPC-Web and the Connect protocols,
+// for the browser code generated by the standard tooling.
+//
+// The function is named by the last element of the path (/package.Service/Method).
+//
+// gRPC-Web (application/grpc-web[+proto|+json]) frames the messages,
+// and sends the status in a trailer frame at the end of the body;
+// the application/grpc-web-text variants are base64 encoded, in separately padded chunks.
+//
+// Connect unary (application/proto, application/json) has the message as the body,
+// and the error as a JSON object with the HTTP status of the error;
+// it is refused for the streaming methods, if the Client implements MethodInfo.
+// Connect streaming (application/connect+proto, application/connect+json) has enveloped messages,
+// and an end-of-stream JSON message at the end of the response.
+type GatewayHandler struct {
+	Client
+	*slog.Logger
+	GetLogger func(context.Context) *slog.Logger
+	// JSON is the codec of the JSON messages.
+	JSON JSONCodec
+	// AllowedOrigins are the origins of the browser code allowed to call the functions (CORS),
+	// "*" allows any origin.
+	AllowedOrigins []string
+	Timeout        time.Duration
+}
+
+// messageCodec marshals and unmarshals one message.
+type messageCodec interface {
+	Marshal(any) ([]byte, error)
+	Unmarshal([]byte, any) error
+}
+
+const (
+	frameCompressed = 0x01
+	frameEndStream  = 0x02 // Connect end-of-stream
+	frameTrailer    = 0x80 // gRPC-Web trailer
+
+	maxFrameSize = 64 << 20
+)
+
+// The CORS headers of the gRPC-Web and the Connect protocols.
+const (
+	corsAllowHeaders = "Content-Type, Content-Encoding, Authorization, Grpc-Timeout, X-Grpc-Web, X-User-Agent, " +
+		"Connect-Protocol-Version, Connect-Timeout-Ms, Connect-Content-Encoding, Connect-Accept-Encoding"
+	corsExposeHeaders = "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin, " +
+		"Connect-Content-Encoding, Connect-Accept-Encoding"
+)
+
+func (h GatewayHandler) getLogger(ctx context.Context) *slog.Logger {
+	if h.GetLogger != nil {
+		if lgr := h.GetLogger(ctx); lgr != nil {
+			return lgr
+		}
+	}
+	return h.Logger
+}
+
+func (h GatewayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && h.allowedOrigin(origin) {
+		hdr := w.Header()
+		hdr.Add("Vary", "Origin")
+		hdr.Set("Access-Control-Allow-Origin", origin)
+		hdr.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			// preflight
+			hdr.Set("Access-Control-Allow-Methods", "POST")
+			hdr.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			hdr.Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	if r.Method != "POST" {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	logger := h.getLogger(ctx)
+	if u, p, ok := r.BasicAuth(); ok {
+		logger.Info("basicAuth", "username", u)
+		ctx = WithBasicAuth(ctx, u, p)
+	}
+	name := path.Base(r.URL.Path)
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(mt, "application/grpc-web"):
+		h.serveGRPCWeb(ctx, w, r, name, mt)
+	case strings.HasPrefix(mt, "application/connect+"):
+		h.serveConnectStream(ctx, w, r, name, mt)
+	case mt == "application/proto" || mt == "application/json":
+		h.serveConnectUnary(ctx, w, r, name, mt)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content type %q", mt), http.StatusUnsupportedMediaType)
+	}
+}
+
+// allowedOrigin reports whether the origin is in AllowedOrigins.
+func (h GatewayHandler) allowedOrigin(origin string) bool {
+	for _, o := range h.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// messageCodec returns the codec for the subtype suffix (proto or json) of the media type.
+func (h GatewayHandler) messageCodec(mediaType string) messageCodec {
+	if _, suffix, _ := strings.Cut(mediaType, "+"); suffix == "json" || mediaType == "application/json" {
+		return h.JSON
+	}
+	return ProtobufCodec{}
+}
+
+// withTimeout returns the context with the timeout (h.Timeout or DefaultTimeout if not positive),
+// if it has no deadline yet.
+func (h GatewayHandler) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	if timeout <= 0 {
+		if timeout = h.Timeout; timeout == 0 {
+			timeout = DefaultTimeout
+		}
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (h GatewayHandler) serveGRPCWeb(ctx context.Context, w http.ResponseWriter, r *http.Request, name, mediaType string) {
+	logger := h.getLogger(ctx)
+	text := strings.HasPrefix(mediaType, "application/grpc-web-text")
+	timeout, _ := parseGRPCTimeout(r.Header.Get("Grpc-Timeout"))
+	ctx, cancel := h.withTimeout(ctx, timeout)
+	defer cancel()
+	body := io.Reader(r.Body)
+	if text {
+		body = &base64Chunks{r: bufio.NewReader(body)}
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(200)
+	rc := http.NewResponseController(w)
+	var buf []byte
+	writeFrame := func(flags byte, msg []byte) error {
+		buf = appendFrame(buf[:0], flags, msg)
+		var err error
+		if text {
+			_, err = io.WriteString(w, base64.StdEncoding.EncodeToString(buf))
+		} else {
+			_, err = w.Write(buf)
+		}
+		if err != nil {
+			return err
+		}
+		_ = rc.Flush()
+		return nil
+	}
+	err := h.stream(ctx, w, name, body, h.messageCodec(mediaType), func(b []byte) error { return writeFrame(0, b) })
+	if err != nil {
+		logger.Error("call", "name", name, "error", err)
+	}
+	if err = writeFrame(frameTrailer, grpcWebTrailer(err)); err != nil {
+		logger.Error("write trailer", "error", err)
+	}
+}
+
+func (h GatewayHandler) serveConnectStream(ctx context.Context, w http.ResponseWriter, r *http.Request, name, mediaType string) {
+	logger := h.getLogger(ctx)
+	var timeout time.Duration
+	if ms, err := strconv.ParseInt(r.Header.Get("Connect-Timeout-Ms"), 10, 64); err == nil {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	ctx, cancel := h.withTimeout(ctx, timeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(200)
+	rc := http.NewResponseController(w)
+	var buf []byte
+	writeFrame := func(flags byte, msg []byte) error {
+		buf = appendFrame(buf[:0], flags, msg)
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		_ = rc.Flush()
+		return nil
+	}
+	err := h.stream(ctx, w, name, r.Body, h.messageCodec(mediaType), func(b []byte) error { return writeFrame(0, b) })
+	var end struct {
+		Error *connectError `json:"error,omitempty"`
+	}
+	if err != nil {
+		logger.Error("call", "name", name, "error", err)
+		end.Error = newConnectError(err)
+	}
+	b, err := json.Marshal(end)
+	if err == nil {
+		err = writeFrame(frameEndStream, b)
+	}
+	if err != nil {
+		logger.Error("write end-of-stream", "error", err)
+	}
+}
+
+func (h GatewayHandler) serveConnectUnary(ctx context.Context, w http.ResponseWriter, r *http.Request, name, mediaType string) {
+	logger := h.getLogger(ctx)
+	var timeout time.Duration
+	if ms, err := strconv.ParseInt(r.Header.Get("Connect-Timeout-Ms"), 10, 64); err == nil {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	ctx, cancel := h.withTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := func() ([]byte, error) {
+		if mi, ok := h.Client.(MethodInfo); ok {
+			if cs, ss := mi.Streaming(name); cs || ss {
+				return nil, status.Errorf(codes.Unimplemented, "%s is a streaming method: use the streaming protocol", name)
+			}
+		}
+		body := io.Reader(r.Body)
+		switch enc := r.Header.Get("Content-Encoding"); enc {
+		case "", "identity":
+		case "gzip":
+			zr, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "gzip: %v", err)
+			}
+			defer zr.Close()
+			body = zr
+		default:
+			return nil, status.Errorf(codes.Unimplemented, "unsupported Content-Encoding %q", enc)
+		}
+		b, err := readAllLimit(body, maxFrameSize)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "read: %v", err)
+		}
+		inp := h.Input(name)
+		if inp == nil {
+			return nil, status.Errorf(codes.Unimplemented, "no unmarshaler for %q: %v", name, ErrNotFound)
+		}
+		mc := h.messageCodec(mediaType)
+		if err = mc.Unmarshal(b, inp); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unmarshal: %v", err)
+		}
+		recv, err := h.Call(name, ctx, inp)
+		if err != nil {
+			return nil, err
+		}
+		part, err := recv.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = recv.Recv(); err == nil {
+			return nil, status.Error(codes.Unimplemented, "more than one response: use the streaming protocol")
+		} else if !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if b, err = mc.Marshal(part); err != nil {
+			return nil, status.Errorf(codes.Internal, "marshal: %v", err)
+		}
+		return b, nil
+	}()
+	if err != nil {
+		logger.Error("call", "name", name, "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(StatusCodeFromError(err))
+		json.NewEncoder(w).Encode(newConnectError(err))
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(200)
+	if _, err = w.Write(out); err != nil {
+		logger.Error("write", "error", err)
+	}
+}
+
+// stream calls the named function with the inputs read from r as frames,
+// and gives the marshaled parts to write.
+//
+// It returns the error of the call.
+func (h GatewayHandler) stream(ctx context.Context, w http.ResponseWriter, name string, r io.Reader, mc messageCodec, write func([]byte) error) error {
+	if h.Input(name) == nil {
+		return status.Errorf(codes.Unimplemented, "no unmarshaler for %q: %v", name, ErrNotFound)
+	}
+	ctx, stream, stop, err := callSending(ctx, w, h.Client, name, func(stream Sender) error {
+		return h.sendFrames(name, r, mc, stream)
+	}, h.getLogger(ctx))
+	defer stop()
+	if err != nil {
+		return err
+	}
+	for part, err := range All(stream) {
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil && !errors.Is(err, cause) {
+				err = fmt.Errorf("%w: %w", cause, err)
+			}
+			return err
+		}
+		b, err := mc.Marshal(part)
+		if err != nil {
+			return status.Errorf(codes.Internal, "marshal: %v", err)
+		}
+		if err = write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendFrames reads the framed inputs from r, and Sends them as they are read.
+func (h GatewayHandler) sendFrames(name string, r io.Reader, mc messageCodec, stream Sender) error {
+	for {
+		flags, msg, err := readFrame(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return status.Errorf(codes.InvalidArgument, "read frame: %v", err)
+		}
+		if flags&frameCompressed != 0 {
+			return status.Error(codes.Unimplemented, "compressed messages are not supported")
+		}
+		inp := h.Input(name)
+		if err = mc.Unmarshal(msg, inp); err != nil {
+			return status.Errorf(codes.InvalidArgument, "unmarshal: %v", err)
+		}
+		if err = stream.Send(inp); err != nil {
+			return fmt.Errorf("send: %w", err)
+		}
+	}
+	return stream.CloseSend()
+}
+
+// readFrame reads a length-prefixed message: the flags byte, the big-endian 4 bytes length and the message.
+//
+// Returns io.EOF at the end of the stream.
+func readFrame(r io.Reader) (flags byte, msg []byte, err error) {
+	var hdr [5]byte
+	if _, err = io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxFrameSize {
+		return 0, nil, status.Errorf(codes.ResourceExhausted, "message size %d is bigger than %d", n, maxFrameSize)
+	}
+	msg = make([]byte, n)
+	if _, err = io.ReadFull(r, msg); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return hdr[0], msg, nil
+}
+
+// appendFrame appends the length-prefixed message to b.
+func appendFrame(b []byte, flags byte, msg []byte) []byte {
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// grpcWebTrailer returns the gRPC-Web trailer of the error: the grpc-status, grpc-message
+// and grpc-status-details-bin headers.
+func grpcWebTrailer(err error) []byte {
+	st := toStatus(err)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "grpc-status: %d\r\n", st.Code())
+	if msg := st.Message(); msg != "" {
+		buf.WriteString("grpc-message: " + percentEncode(msg) + "\r\n")
+	}
+	if len(st.Proto().GetDetails()) != 0 {
+		if b, err := proto.Marshal(st.Proto()); err == nil {
+			buf.WriteString("grpc-status-details-bin: " + base64.RawStdEncoding.EncodeToString(b) + "\r\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// parseGRPCTimeout parses the grpc-timeout header (an at most 8 digits long integer and a unit).
+func parseGRPCTimeout(s string) (time.Duration, bool) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 'H':
+		unit = time.Hour
+	case 'M':
+		unit = time.Minute
+	case 'S':
+		unit = time.Second
+	case 'm':
+		unit = time.Millisecond
+	case 'u':
+		unit = time.Microsecond
+	case 'n':
+		unit = time.Nanosecond
+	default:
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// connectError is the error of the Connect protocol.
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+type connectErrorDetail struct {
+	Type  string          `json:"type"`
+	Value string          `json:"value"`
+	Debug json.RawMessage `json:"debug,omitempty"`
+}
+
+func newConnectError(err error) *connectError {
+	st := toStatus(err)
+	ce := connectError{Code: connectCode(st.Code()), Message: st.Message()}
+	for _, a := range st.Proto().GetDetails() {
+		typeURL := a.GetTypeUrl()
+		d := connectErrorDetail{
+			Type:  typeURL[strings.LastIndexByte(typeURL, '/')+1:],
+			Value: base64.RawStdEncoding.EncodeToString(a.GetValue()),
+		}
+		if b, err := protojson.Marshal(a); err == nil {
+			d.Debug = b
+		}
+		ce.Details = append(ce.Details, d)
+	}
+	return &ce
+}
+
+// connectCode returns the Connect name of the code (snake_case of the gRPC name).
+func connectCode(code codes.Code) string {
+	s := code.String()
+	var buf strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i != 0 {
+				buf.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// base64Chunks decodes the base64 stream of separately padded chunks, 4 bytes (a quantum) at a time,
+// as a padded chunk may be followed by an other.
+type base64Chunks struct {
+	r   io.Reader
+	out []byte
+	in  [4]byte
+	buf [3]byte
+}
+
+func (d *base64Chunks) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if _, err := io.ReadFull(d.r, d.in[:]); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = fmt.Errorf("truncated base64 quantum: %w", err)
+			}
+			return 0, err
+		}
+		n, err := base64.StdEncoding.Decode(d.buf[:], d.in[:])
+		if err != nil {
+			return 0, err
+		}
+		d.out = d.buf[:n]
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
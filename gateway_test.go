@@ -0,0 +1,257 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// watchInfoClient is a watchClient which describes Watch as server streaming by MethodInfo.
+type watchInfoClient struct{ watchClient }
+
+func (watchInfoClient) Tags(name string) []string          { return nil }
+func (watchInfoClient) FullMethod(name string) string      { return "/grpc.health.v1.Health/" + name }
+func (watchInfoClient) Streaming(name string) (bool, bool) { return false, name == "Watch" }
+func (watchInfoClient) Descriptors(name string) (input, output protoreflect.MessageDescriptor) {
+	return nil, nil
+}
+func (watchInfoClient) Comments(name string) string { return "" }
+
+var _ MethodInfo = watchInfoClient{}
+
+func TestGatewayHandler(t *testing.T) {
+	h := GatewayHandler{Client: watchClient{}, Logger: zlog.NewT(t).SLog()}
+	frame := func(codec messageCodec, service string) []byte {
+		b, err := codec.Marshal(&healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return appendFrame(nil, 0, b)
+	}
+	// readFrames returns the messages, and the trailer/end-of-stream frame.
+	readFrames := func(r io.Reader) (msgs []string, end string) {
+		for {
+			flags, msg, err := readFrame(r)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					t.Fatal(err)
+				}
+				return msgs, end
+			}
+			if flags&(frameTrailer|frameEndStream) != 0 {
+				end = string(msg)
+			} else {
+				var resp healthpb.HealthCheckResponse
+				if err := proto.Unmarshal(msg, &resp); err != nil {
+					if err = h.JSON.Unmarshal(msg, &resp); err != nil {
+						t.Fatal(err)
+					}
+				}
+				msgs = append(msgs, resp.GetStatus().String())
+			}
+		}
+	}
+
+	for tN, tC := range map[string]struct {
+		ContentType string
+		Service     string
+		End         string
+		Want        []string
+		Chunked     bool
+	}{
+		"grpc-web":      {ContentType: "application/grpc-web+proto", Service: "ok", End: "grpc-status: 0\r\n", Want: []string{"SERVING", "NOT_SERVING"}},
+		"grpc-web-text": {ContentType: "application/grpc-web-text", Service: "fail", End: "grpc-status: 14\r\ngrpc-message: failed\r\n", Want: []string{"SERVING", "NOT_SERVING"}},
+		"grpc-web-text-chunked": {ContentType: "application/grpc-web-text", Service: "ok", End: "grpc-status: 0\r\n", Want: []string{"SERVING", "NOT_SERVING"},
+			Chunked: true},
+		"connect":      {ContentType: "application/connect+json", Service: "ok", End: "{}", Want: []string{"SERVING", "NOT_SERVING"}},
+		"connect-fail": {ContentType: "application/connect+proto", Service: "fail", End: `{"error":{"code":"unavailable","message":"failed"}}`, Want: []string{"SERVING", "NOT_SERVING"}},
+	} {
+		var codec messageCodec = ProtobufCodec{}
+		if strings.HasSuffix(tC.ContentType, "+json") {
+			codec = h.JSON
+		}
+		body := frame(codec, tC.Service)
+		text := strings.Contains(tC.ContentType, "-text")
+		if tC.Chunked {
+			// the header and the message of the frame are encoded separately, each padded
+			body = []byte(base64.StdEncoding.EncodeToString(body[:5]) + base64.StdEncoding.EncodeToString(body[5:]))
+		} else if text {
+			body = []byte(base64.StdEncoding.EncodeToString(body))
+		}
+		r := httptest.NewRequest("POST", "/grpc.health.v1.Health/Watch", bytes.NewReader(body))
+		r.Header.Set("Content-Type", tC.ContentType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 200 || w.Header().Get("Content-Type") != tC.ContentType {
+			t.Fatalf("%s: got %d %q: %q", tN, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		var resp io.Reader = w.Body
+		if text {
+			// the frames are encoded separately, each padded
+			var buf bytes.Buffer
+			for s := w.Body.String(); s != ""; {
+				i := strings.IndexByte(s, '=')
+				if i < 0 {
+					i = len(s)
+				}
+				for i < len(s) && s[i] == '=' {
+					i++
+				}
+				b, err := base64.StdEncoding.DecodeString(s[:i])
+				if err != nil {
+					t.Fatalf("%s: %q: %+v", tN, s[:i], err)
+				}
+				buf.Write(b)
+				s = s[i:]
+			}
+			resp = &buf
+		}
+		msgs, end := readFrames(resp)
+		if strings.Join(msgs, ",") != strings.Join(tC.Want, ",") {
+			t.Errorf("%s: got %q, wanted %q", tN, msgs, tC.Want)
+		}
+		if end != tC.End {
+			t.Errorf("%s: got end %q, wanted %q", tN, end, tC.End)
+		}
+	}
+
+	// a truncated frame to a unary method
+	for _, ct := range []string{"application/grpc-web+proto", "application/connect+proto"} {
+		body := frame(ProtobufCodec{}, "ok")
+		r := httptest.NewRequest("POST", "/grpc.health.v1.Health/Check", bytes.NewReader(body[:len(body)-1]))
+		r.Header.Set("Content-Type", ct)
+		w := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() { defer close(done); h.ServeHTTP(w, r) }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: handler hangs", ct)
+		}
+		msgs, end := readFrames(w.Body)
+		if len(msgs) != 0 || !strings.HasPrefix(end, "grpc-status: 3\r\n") && !strings.HasPrefix(end, `{"error":{"code":"invalid_argument"`) {
+			t.Errorf("%s: got %q, %q, wanted invalid argument", ct, msgs, end)
+		}
+	}
+
+	// the unary protocol for a streaming method is refused before calling it
+	{
+		r := httptest.NewRequest("POST", "/grpc.health.v1.Health/Watch", strings.NewReader(`{"service":"ok"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		GatewayHandler{Client: watchInfoClient{}, Logger: h.Logger}.ServeHTTP(w, r)
+		if w.Code != 501 || !strings.Contains(w.Body.String(), "streaming method") {
+			t.Errorf("unary Watch: got %d: %q", w.Code, w.Body.String())
+		}
+	}
+
+	for tN, tC := range map[string]struct {
+		ContentType string
+		Service     string
+		Code        int
+		Want        string
+	}{
+		"json":  {ContentType: "application/json", Service: "ok", Code: 200, Want: `{"status":"SERVING"}`},
+		"proto": {ContentType: "application/proto", Service: "ok", Code: 200},
+		"fail":  {ContentType: "application/json", Service: "fail", Code: 503, Want: `{"code":"unavailable","message":"failed"}`},
+	} {
+		var codec messageCodec = ProtobufCodec{}
+		if tC.ContentType == "application/json" {
+			codec = h.JSON
+		}
+		body, err := codec.Marshal(&healthpb.HealthCheckRequest{Service: tC.Service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("POST", "/grpc.health.v1.Health/Check", bytes.NewReader(body))
+		r.Header.Set("Content-Type", tC.ContentType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tC.Code {
+			t.Fatalf("%s: got %d: %q", tN, w.Code, w.Body.String())
+		}
+		if tC.Want == "" {
+			var resp healthpb.HealthCheckResponse
+			if err := proto.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("%s: got %v (%+v)", tN, &resp, err)
+			}
+			continue
+		}
+		var got any
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %q: %+v", tN, w.Body.String(), err)
+		}
+		if g, _ := json.Marshal(got); string(g) != tC.Want {
+			t.Errorf("%s: got %s, wanted %s", tN, g, tC.Want)
+		}
+	}
+}
+
+func TestGatewayCORS(t *testing.T) {
+	h := GatewayHandler{Client: watchClient{}, Logger: zlog.NewT(t).SLog(), AllowedOrigins: []string{"https://example.com"}}
+	for tN, tC := range map[string]struct {
+		Method, Origin string
+		Code           int
+		Allowed        bool
+	}{
+		"preflight":  {Method: "OPTIONS", Origin: "https://example.com", Code: 204, Allowed: true},
+		"forbidden":  {Method: "OPTIONS", Origin: "https://example.org", Code: 405},
+		"post":       {Method: "POST", Origin: "https://example.com", Code: 200, Allowed: true},
+		"postOther":  {Method: "POST", Origin: "https://example.org", Code: 200},
+		"sameOrigin": {Method: "POST", Code: 200},
+	} {
+		var body io.Reader
+		if tC.Method == "POST" {
+			b, err := ProtobufCodec{}.Marshal(&healthpb.HealthCheckRequest{Service: "ok"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = bytes.NewReader(appendFrame(nil, 0, b))
+		}
+		r := httptest.NewRequest(tC.Method, "/grpc.health.v1.Health/Check", body)
+		if tC.Method == "POST" {
+			r.Header.Set("Content-Type", "application/grpc-web+proto")
+		} else {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+			r.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+		}
+		if tC.Origin != "" {
+			r.Header.Set("Origin", tC.Origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tC.Code {
+			t.Errorf("%s: got %d, wanted %d: %q", tN, w.Code, tC.Code, w.Body.String())
+		}
+		hdr := w.Header()
+		if got := hdr.Get("Access-Control-Allow-Origin"); got != "" != tC.Allowed || tC.Allowed && got != tC.Origin {
+			t.Errorf("%s: got Access-Control-Allow-Origin %q", tN, got)
+		}
+		if !tC.Allowed {
+			continue
+		}
+		if got := hdr.Get("Access-Control-Expose-Headers"); !strings.Contains(got, "Grpc-Status") || !strings.Contains(got, "Grpc-Message") {
+			t.Errorf("%s: got Access-Control-Expose-Headers %q", tN, got)
+		}
+		if tC.Method == "OPTIONS" {
+			if got := hdr.Get("Access-Control-Allow-Headers"); !strings.Contains(got, "X-Grpc-Web") || !strings.Contains(got, "Connect-Protocol-Version") {
+				t.Errorf("%s: got Access-Control-Allow-Headers %q", tN, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// JSONCodec encodes and decodes the inputs and outputs as JSON.
+//
+// proto.Messages are encoded and decoded with protojson (oneofs, well-known types,
+// enums as strings, 64-bit integers as strings), anything else with encoding/json.
+// Decoding falls back to a lenient mapstructure-based decoding in both cases
+// (which rejects the unknown fields of the messages, if RejectUnknown).
+//
+// The zero JSONCodec uses the proto field names, and ignores the unknown fields.
+type JSONCodec struct {
+	// UseJSONNames uses the lowerCamelCase JSON names instead of the proto field names in the output.
+	UseJSONNames bool
+	// EmitUnpopulated emits the unpopulated fields of the messages, too.
+	EmitUnpopulated bool
+	// RejectUnknown returns an error for the unknown fields of the input messages, instead of ignoring them.
+	RejectUnknown bool
+}
+
+var _ Codec = JSONCodec{}
+
+func (c JSONCodec) ContentType() string { return "application/json" }
+
+// NewDecoder returns a Decoder of the NDJSON stream or JSON array of the inputs.
+func (c JSONCodec) NewDecoder(r io.Reader) Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &jsonDecoder{codec: c, br: br}
+}
+
+// NewEncoder returns an Encoder writing the parts as NDJSON.
+func (c JSONCodec) NewEncoder(w io.Writer) Encoder { return jsonEncoder{codec: c, w: w} }
+
+type jsonEncoder struct {
+	w     io.Writer
+	codec JSONCodec
+}
+
+func (e jsonEncoder) Encode(v any) error { return e.codec.Encode(e.w, v) }
+
+type jsonDecoder struct {
+	br      *bufio.Reader
+	dec     *json.Decoder
+	codec   JSONCodec
+	started bool
+	array   bool
+}
+
+func (d *jsonDecoder) Decode(v any) error {
+	if !d.started {
+		d.started = true
+		for {
+			c, err := d.br.ReadByte()
+			if err != nil {
+				return err
+			}
+			if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+				continue
+			}
+			d.br.UnreadByte()
+			d.array = c == '['
+			break
+		}
+		d.dec = json.NewDecoder(d.br)
+		if d.array {
+			if _, err := d.dec.Token(); err != nil {
+				return err
+			}
+		}
+	}
+	if d.dec == nil || d.array && !d.dec.More() {
+		return io.EOF
+	}
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		if !d.array && errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return fmt.Errorf("decode: %w", err)
+	}
+	return d.codec.Unmarshal(raw, v)
+}
+
+// Marshal v to JSON.
+func (c JSONCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return c.marshalOptions().Marshal(m)
+	}
+	return json.Marshal(v)
+}
+
+func (c JSONCodec) marshalOptions() protojson.MarshalOptions {
+	return protojson.MarshalOptions{UseProtoNames: !c.UseJSONNames, EmitUnpopulated: c.EmitUnpopulated}
+}
+
+// Encode v as one line of JSON to w.
+func (c JSONCodec) Encode(w io.Writer, v any) error {
+	b, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Unmarshal b into v, falling back to the lenient weakDecode.
+//
+// The messages are decoded with protojson rejecting the unknown fields first,
+// so the keys not known by protojson (such as the Go field names) are decoded by weakDecode;
+// and then, unless RejectUnknown, ignoring the unknown fields.
+func (c JSONCodec) Unmarshal(b []byte, v any) error {
+	var err error
+	var errorUnused bool
+	pm, isProto := v.(proto.Message)
+	if isProto {
+		err = protojson.Unmarshal(b, pm)
+		errorUnused = c.RejectUnknown
+	} else {
+		err = json.Unmarshal(b, v)
+	}
+	if err == nil {
+		return nil
+	}
+	origErr := fmt.Errorf("%s: %w", limitWidth(b, 1024), err)
+	m := mapPool.Get().(map[string]any)
+	defer func() {
+		for k := range m {
+			delete(m, k)
+		}
+		mapPool.Put(m)
+	}()
+	if err = json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("decode %s: %w (was: %+v)", limitWidth(b, 1024), err, origErr)
+	}
+	if err = weakDecode(m, v, errorUnused); err != nil {
+		if isProto && !c.RejectUnknown && (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, pm) == nil {
+			return nil
+		}
+		return fmt.Errorf("%w (was: %+v)", err, origErr)
+	}
+	return nil
+}
+
+// jsonValue is a json.Marshaler, for embedding v in encoding/json encoded values.
+type jsonValue struct {
+	v     any
+	codec JSONCodec
+}
+
+func (v jsonValue) MarshalJSON() ([]byte, error) { return v.codec.Marshal(v.v) }
+
+// value returns v as a json.Marshaler using the codec.
+func (c JSONCodec) value(v any) json.Marshaler { return jsonValue{v: v, codec: c} }
+
+// fieldName returns the name of the field in the JSON encoding, as protojson names it.
+func (c JSONCodec) fieldName(fd protoreflect.FieldDescriptor) string {
+	if c.UseJSONNames {
+		return fd.JSONName()
+	}
+	return fd.TextName()
+}
+
+// fields returns the slice and non-slice fields of the part, as SliceFields does,
+// with their names and values as the part is encoded.
+//
+// For a proto.Message the values are json.RawMessages,
+// and the unpopulated non-list fields are returned with a nil json.RawMessage.
+func (c JSONCodec) fields(part any) (slice, notSlice []Field, err error) {
+	m, ok := part.(proto.Message)
+	if !ok {
+		slice, notSlice = SliceFields(part, "json")
+		return slice, notSlice, nil
+	}
+	b, err := c.Marshal(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	var raws map[string]json.RawMessage
+	if err = json.Unmarshal(b, &raws); err != nil {
+		return nil, nil, err
+	}
+	fds := m.ProtoReflect().Descriptor().Fields()
+	for i := range fds.Len() {
+		fd := fds.Get(i)
+		f := Field{Name: string(fd.Name()), TagName: c.fieldName(fd)}
+		raw := raws[f.TagName]
+		f.Value = raw
+		if !fd.IsList() {
+			notSlice = append(notSlice, f)
+		} else if raw != nil {
+			slice = append(slice, f)
+		}
+	}
+	return slice, notSlice, nil
+}
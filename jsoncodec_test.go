@@ -0,0 +1,89 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestJSONCodec(t *testing.T) {
+	var buf bytes.Buffer
+	for tN, tC := range map[string]struct {
+		Value any
+		Codec JSONCodec
+		Want  string
+	}{
+		"enum":     {Value: &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, Want: `{"status":"SERVING"}`},
+		"duration": {Value: &errdetails.RetryInfo{RetryDelay: durationpb.New(1500_000_000)}, Want: `{"retry_delay":"1.500s"}`},
+		"jsonName": {Value: &errdetails.RetryInfo{RetryDelay: durationpb.New(1e9)}, Codec: JSONCodec{UseJSONNames: true}, Want: `{"retryDelay":"1s"}`},
+		"unpopulated": {Value: &healthpb.HealthCheckRequest{}, Codec: JSONCodec{EmitUnpopulated: true},
+			Want: `{"service":""}`},
+		"notProto": {Value: struct{ A int64 }{A: 1}, Want: `{"A":1}`},
+	} {
+		buf.Reset()
+		if err := tC.Codec.Encode(&buf, tC.Value); err != nil {
+			t.Fatalf("%s: %+v", tN, err)
+		}
+		if got := strings.ReplaceAll(strings.TrimSpace(buf.String()), " ", ""); got != tC.Want {
+			t.Errorf("%s: got %s, wanted %s", tN, got, tC.Want)
+		}
+	}
+
+	var req healthpb.HealthCheckRequest
+	if err := (JSONCodec{RejectUnknown: true}).Unmarshal([]byte(`{"service":"a","unknown":1}`), &req); err == nil {
+		t.Error("unknown field accepted")
+	}
+	if err := (JSONCodec{}).Unmarshal([]byte(`{"service":"a","unknown":1}`), &req); err != nil {
+		t.Error(err)
+	} else if req.Service != "a" {
+		t.Errorf("got %q", req.Service)
+	}
+	if err := (JSONCodec{}).Unmarshal([]byte(`{"Service":"b"}`), &req); err != nil {
+		t.Error(err)
+	} else if req.Service != "b" {
+		t.Errorf("got %q", req.Service)
+	}
+	var ri errdetails.RetryInfo
+	if err := (JSONCodec{}).Unmarshal([]byte(`{"retry_delay":"2s","unknown":1}`), &ri); err != nil {
+		t.Error(err)
+	} else if d := ri.GetRetryDelay().AsDuration(); d != 2e9 {
+		t.Errorf("got %v", d)
+	}
+	if err := (JSONCodec{}).Unmarshal([]byte(`{"retryDelay":"3s"}`), &ri); err != nil {
+		t.Error(err)
+	} else if d := ri.GetRetryDelay().AsDuration(); d != 3e9 {
+		t.Errorf("got %v", d)
+	}
+}
+
+func TestMergeProto(t *testing.T) {
+	parts := []any{
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "a", Description: "x"}}},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "b"}}},
+	}
+	var buf bytes.Buffer
+	recv := &receiver{parts: parts}
+	first, _ := recv.Recv()
+	if err := mergeStreams(&buf, mergeConfig{}, first, recv, zlog.NewT(t).SLog()); err != nil {
+		t.Fatal(err)
+	}
+	var got errdetails.BadRequest
+	if err := (JSONCodec{}).Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%s: %+v", buf.String(), err)
+	}
+	want := &errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+		{Field: "a", Description: "x"}, {Field: "b"}}}
+	if !proto.Equal(&got, want) {
+		t.Errorf("got %s, wanted %v", buf.String(), want)
+	}
+}
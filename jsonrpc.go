@@ -5,17 +5,19 @@
 package grpcer
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"path"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,7 +27,6 @@ import (
 	"github.com/klauspost/compress/gzhttp"
 	"github.com/mitchellh/mapstructure"
 	"github.com/tgulacsi/go/iohlp"
-
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -35,6 +36,8 @@ var (
 	MaxLogWidth    = 1 << 10
 
 	ErrNotFound = errors.New("not found")
+	// ErrWrongType is returned for a part or an input of an unexpected type.
+	ErrWrongType = errors.New("wrong type")
 )
 
 type RequestInfo interface {
@@ -46,7 +49,26 @@ type JSONHandler struct {
 	*slog.Logger `json:"-"`
 	GetLogger    func(context.Context) *slog.Logger
 	Timeout      time.Duration
+	// StatusCode returns the HTTP status code for the error (StatusCodeFromError if nil or returns 0).
+	StatusCode func(error) int
+	// KeepAlive is the period of the keep-alive comments in Server-Sent Events mode,
+	// DefaultKeepAlive if 0, no keep-alive if negative.
+	KeepAlive time.Duration
+	// JSON is the codec of the inputs and outputs.
+	JSON JSONCodec
+	// MergeStreams merges the streamed parts into one object by default (can be overridden with merge=0 or merge=1 URL parameter).
 	MergeStreams bool
+	// MergePolicy is the policy of merging the scalar fields of the parts.
+	MergePolicy MergePolicy
+	// TempDir is the directory of the temporary files of merging and exporting (os.TempDir if empty).
+	TempDir string
+	// SpillLimit is the size of the in-memory buffers of merging and exporting,
+	// beyond which they are spilled to TempDir (DefaultSpillLimit if 0, no memory if negative).
+	SpillLimit int
+}
+
+func (h JSONHandler) mergeConfig() mergeConfig {
+	return mergeConfig{TempDir: h.TempDir, SpillLimit: h.SpillLimit, Codec: h.JSON, Policy: h.MergePolicy}
 }
 
 func (h JSONHandler) getLogger(ctx context.Context) *slog.Logger {
@@ -58,16 +80,51 @@ func (h JSONHandler) getLogger(ctx context.Context) *slog.Logger {
 	return h.Logger
 }
 
-func jsonError(w http.ResponseWriter, errMsg string, code int) {
-	w.Header().Set("Content-Type", "application/json")
+func jsonError(w http.ResponseWriter, err error, code int) {
+	codecError(w, JSONCodec{}, err, code)
+}
+
+// httpError writes the error encoded with the codec, with the status code returned by StatusCode,
+// with a Retry-After header for 429 and 503 responses.
+func (h JSONHandler) httpError(w http.ResponseWriter, codec Codec, err error) {
+	var code int
+	if h.StatusCode != nil {
+		code = h.StatusCode(err)
+	}
 	if code == 0 {
-		code = http.StatusInternalServerError
+		code = StatusCodeFromError(err)
 	}
-	w.WriteHeader(code)
-	e := struct {
-		Error string
-	}{Error: errMsg}
-	json.NewEncoder(w).Encode(e)
+	if code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+		d := retryDelay(err)
+		if d <= 0 && code == http.StatusTooManyRequests {
+			d = time.Second
+		}
+		if d > 0 {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10))
+		}
+	}
+	codecError(w, codec, err, code)
+}
+
+// lookupCodec returns the registered Codec for the media type (the JSON codec of the handler for JSON), or nil.
+func (h JSONHandler) lookupCodec(mediaType string) Codec {
+	c := LookupCodec(mediaType)
+	if _, ok := c.(JSONCodec); ok {
+		return h.JSON
+	}
+	return c
+}
+
+// codecs returns the Codec of the request by its Content-Type (JSON if not registered),
+// and of the response by the Accept header (the request's if not registered).
+func (h JSONHandler) codecs(r *http.Request) (req, resp Codec) {
+	if req = h.lookupCodec(r.Header.Get("Content-Type")); req == nil {
+		req = h.JSON
+	}
+	if resp = acceptCodec(r.Header.Get("Accept"), h.lookupCodec); resp == nil {
+		resp = req
+	}
+	return req, resp
 }
 
 const debugDecodeHook = false
@@ -156,24 +213,22 @@ func (h JSONHandler) DecodeRequest(ctx context.Context, r *http.Request) (Reques
 		body = strings.NewReader("{}")
 	}
 
-	if err = json.NewDecoder(body).Decode(inp); err == nil {
-		return request, inp, nil
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return request, nil, err
 	}
-	logger.Error("decode", "body", body, "error", err)
-	b, _ := ReadHeadTail(io.NewSectionReader(sr, 0, sr.Size()), 1024)
-	origErr := fmt.Errorf("%s: %w", string(b), err)
-	m := mapPool.Get().(map[string]any)
-	defer func() {
-		for k := range m {
-			delete(m, k)
-		}
-		mapPool.Put(m)
-	}()
-	if err = json.NewDecoder(sr).Decode(&m); err != nil {
-		return request, nil, fmt.Errorf("decode %s: %w (was: %+v)", string(b), err, origErr)
+	if err = h.JSON.Unmarshal(b, inp); err != nil {
+		logger.Error("decode", "body", limitWidth(b, 1024), "error", err)
+		return request, inp, err
 	}
+	return request, inp, nil
+}
 
-	// mapstruct
+// weakDecode decodes m into inp with mapstructure,
+// after deleting the empty strings and CamelCase-ing the lowercase keys.
+//
+// Unused keys are an error iff errorUnused.
+func weakDecode(m map[string]any, inp any, errorUnused bool) error {
 	for k, v := range m {
 		if s, ok := v.(string); ok && s == "" {
 			delete(m, k)
@@ -181,17 +236,113 @@ func (h JSONHandler) DecodeRequest(ctx context.Context, r *http.Request) (Reques
 		}
 		f, _ := utf8.DecodeRune([]byte(k))
 		if unicode.IsLower(f) {
+			delete(m, k)
 			m[CamelCase(k)] = v
 		}
 	}
 	decConf := msDecConf
 	decConf.Result = inp
-	if dec, err := mapstructure.NewDecoder(&decConf); err != nil {
-		return request, inp, fmt.Errorf("mapstructure.NewDecoder: %w (was: %+v)", err, origErr)
-	} else if err = dec.Decode(m); err != nil {
-		return request, inp, fmt.Errorf("weakdecode(%#v): %w (was: %+v)", m, err, origErr)
+	decConf.ErrorUnused = errorUnused
+	dec, err := mapstructure.NewDecoder(&decConf)
+	if err != nil {
+		return fmt.Errorf("mapstructure.NewDecoder: %w", err)
 	}
-	return request, inp, nil
+	if err = dec.Decode(m); err != nil {
+		return fmt.Errorf("weakdecode(%#v): %w", m, err)
+	}
+	return nil
+}
+
+// streamBody returns the request body as a stream of inputs,
+// iff it is NDJSON (application/x-ndjson) or a JSON array.
+//
+// This is a guess for the Clients not implementing MethodInfo.
+func streamBody(r *http.Request) (*bufio.Reader, bool) {
+	if r.Body == nil {
+		return nil, false
+	}
+	br := bufio.NewReader(r.Body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, r.Body}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/x-ndjson" {
+		return br, true
+	}
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return nil, false
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+		br.UnreadByte()
+		return br, c == '['
+	}
+}
+
+// sendInputs decodes the inputs one-by-one into fresh inputs,
+// and Sends them as they are read.
+func (h JSONHandler) sendInputs(name string, dec Decoder, stream Sender) error {
+	for {
+		inp := h.Input(name)
+		if err := dec.Decode(inp); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return status.Errorf(codes.InvalidArgument, "decode: %v", err)
+		}
+		if err := stream.Send(inp); err != nil {
+			return fmt.Errorf("send: %w", err)
+		}
+	}
+	return stream.CloseSend()
+}
+
+// callSending opens a Stream for the named function, and sends the inputs with send in a new goroutine,
+// while the parts are received.
+//
+// If send fails, ctx is canceled with its error, and the sending side of the stream is closed.
+// The returned stop func must be called after receiving: it stops reading the request body,
+// and waits for send to return.
+// w may be nil, if send does not read the request body.
+func callSending(ctx context.Context, w http.ResponseWriter, c Client, name string, send func(Sender) error, logger *slog.Logger) (context.Context, Stream, func(), error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stream, err := CallStream(c, name, ctx)
+	if err != nil {
+		cancel(nil)
+		return ctx, nil, func() {}, err
+	}
+	var rc *http.ResponseController
+	if w != nil {
+		rc = http.NewResponseController(w)
+		// HTTP/1 would not allow reading the request body after writing the response
+		_ = rc.EnableFullDuplex()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := send(stream); err != nil {
+			logger.Error("send", "name", name, "error", err)
+			cancel(err)
+			_ = stream.CloseSend()
+		}
+	}()
+	return ctx, stream, func() {
+		cancel(nil)
+		select {
+		case <-done:
+		default:
+			if rc == nil {
+				<-done
+				return
+			}
+			// unblock the reading of the request body
+			_ = rc.SetReadDeadline(time.Now())
+			<-done
+		}
+	}, nil
 }
 
 type requestInfo struct {
@@ -209,18 +360,55 @@ func (h JSONHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 	logger := h.getLogger(ctx)
-	request, inp, err := h.DecodeRequest(ctx, r)
-	if err != nil {
-		jsonError(w, err.Error(), http.StatusBadRequest)
+	var inp any
+	name := path.Base(r.URL.Path)
+	if name == MethodsPath && r.Method == "GET" {
+		h.serveMethods(w, r)
 		return
 	}
-	r.Body.Close()
-	name := request.Name()
+	reqCodec, respCodec := h.codecs(r)
+	var inputs Decoder
+	var streamed bool
+	if _, ok := reqCodec.(JSONCodec); ok {
+		if mi, ok := h.Client.(MethodInfo); ok {
+			// the client streaming methods take the inputs as a stream, the others one input
+			if cs, _ := mi.Streaming(name); cs {
+				streamed, inputs = true, h.JSON.NewDecoder(r.Body)
+			}
+		} else {
+			var br *bufio.Reader
+			if br, streamed = streamBody(r); streamed {
+				inputs = h.JSON.NewDecoder(br)
+			}
+		}
+	} else {
+		streamed, inputs = true, reqCodec.NewDecoder(r.Body)
+		if md, ok := inputs.(MethodDecoder); ok {
+			var err error
+			if name, err = md.Method(); err != nil {
+				codecError(w, respCodec, err, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if streamed {
+		if h.Input(name) == nil {
+			codecError(w, respCodec, fmt.Errorf("no unmarshaler for %q: %w", name, ErrNotFound), http.StatusBadRequest)
+			return
+		}
+	} else {
+		request, input, err := h.DecodeRequest(ctx, r)
+		if err != nil {
+			codecError(w, respCodec, err, http.StatusBadRequest)
+			return
+		}
+		r.Body.Close()
+		name, inp = request.Name(), input
+	}
 
 	ht := iohlp.HeadTailKeeper{Limit: MaxLogWidth / 2}
-	jenc := json.NewEncoder(&ht)
 	{
-		_ = jenc.Encode(inp)
+		_ = h.JSON.Encode(&ht, inp)
 		u, p, ok := r.BasicAuth()
 		logger.Info("basicAuth", "username", u)
 		if ok {
@@ -239,65 +427,136 @@ func (h JSONHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	dl, _ := ctx.Deadline()
-	logger.Info("call", "name", name, "deadline", dl)
-
-	recv, err := h.Call(name, ctx, inp)
+	logger.Info("call", "name", name, "deadline", dl, "streamed", streamed)
+
+	var recv Receiver
+	var err error
+	if streamed {
+		var stop func()
+		ctx, recv, stop, err = callSending(ctx, w, h.Client, name, func(stream Sender) error {
+			return h.sendInputs(name, inputs, stream)
+		}, logger)
+		defer stop()
+	} else {
+		recv, err = h.Call(name, ctx, inp)
+	}
 	if err != nil {
 		logger.Error("call", "name", name, "error", err)
-		jsonError(w, fmt.Sprintf("Call %s: %s", name, err), statusCodeFromError(err))
+		h.httpError(w, respCodec, fmt.Errorf("Call %s: %w", name, err))
 		return
 	}
 
 	part, err := recv.Recv()
 	if err != nil {
+		if cause := context.Cause(ctx); cause != nil && !errors.Is(err, cause) {
+			err = fmt.Errorf("%w: %w", cause, err)
+		}
 		logger.Error("recv", "error", err)
-		jsonError(w, fmt.Sprintf("recv: %s", err), statusCodeFromError(err))
+		h.httpError(w, respCodec, fmt.Errorf("recv: %w", err))
+		return
+	}
+	if wantsSSE(r) {
+		h.serveSSE(ctx, w, part, recv, logger)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
+	if format := r.URL.Query().Get("format"); format == "csv" || format == "xlsx" {
+		h.serveTable(w, format, name, part, recv, logger)
+		return
+	}
+	if _, ok := respCodec.(JSONCodec); !ok {
+		serveCodec(w, respCodec, part, recv, logger)
+		return
+	}
+	// the parts are written one per line, as NDJSON
+	ct := "application/json"
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Accept")); mt == "application/x-ndjson" {
+		ct = mt
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
 	w.WriteHeader(200)
 
 	if m := r.URL.Query().Get("merge"); h.MergeStreams && m != "0" || !h.MergeStreams && m == "1" {
 		ht.Reset()
-		_ = jenc.Encode(part)
+		_ = h.JSON.Encode(&ht, part)
 		logger.Debug("merge", "part", ht.String())
-		if err := mergeStreams(w, part, recv, logger); err != nil {
+		err := mergeStreams(w, h.mergeConfig(), part, recv, logger)
+		if err != nil {
 			logger.Error("mergeStreams", "error", err)
 		}
+		setStatusTrailer(w, err)
 		return
 	}
 
-	enc := json.NewEncoder(w)
-	for {
+	for part, err = range prepend(part, All(recv)) {
+		if err != nil {
+			logger.Error("recv", "error", err)
+			// the last record is the error
+			if encErr := h.JSON.Encode(w, NewErrorBody(err)); encErr != nil {
+				logger.Error("encode", "error", encErr)
+			}
+			break
+		}
 		ht.Reset()
-		_ = jenc.Encode(part)
+		_ = h.JSON.Encode(&ht, part)
 		logger.Debug("cycle", "part", ht.String())
-		if err := enc.Encode(part); err != nil {
+		if err = h.JSON.Encode(w, part); err != nil {
 			logger.Error("encode", part, "error", err)
-			return
+			break
 		}
+	}
+	setStatusTrailer(w, err)
+}
 
-		part, err = recv.Recv()
+// serveCodec writes the received parts encoded with the codec,
+// the error (if any) as the last part, and the status in the trailers.
+func serveCodec(w http.ResponseWriter, codec Codec, part any, recv Receiver, logger *slog.Logger) {
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(200)
+	enc := codec.NewEncoder(w)
+	var err error
+	for part, err = range prepend(part, All(recv)) {
 		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				logger.Error("recv", "error", err)
-			}
+			logger.Error("recv", "error", err)
+			encodeError(enc, err)
+			break
+		}
+		if err = enc.Encode(part); err != nil {
+			logger.Error("encode", "error", err)
 			break
 		}
 	}
+	if closeErr := closeEncoder(enc); closeErr != nil {
+		logger.Error("close encoder", "error", closeErr)
+	}
+	setStatusTrailer(w, err)
+}
+
+// setStatusTrailer sets the Grpc-Status and Grpc-Message trailers (declared before writing the body)
+// to the status of the error, to make a truncated stream detectable.
+func setStatusTrailer(w http.ResponseWriter, err error) {
+	code, msg := "0", ""
+	if err != nil {
+		st, _ := grpcStatus(err)
+		code, msg = strconv.Itoa(int(st.Code())), st.Message()
+	}
+	w.Header().Set("Grpc-Status", code)
+	w.Header().Set("Grpc-Message", percentEncode(msg))
 }
 
-func statusCodeFromError(err error) int {
-	st := status.Convert(errors.Unwrap(err))
-	switch st.Code() {
-	case codes.PermissionDenied, codes.Unauthenticated:
-		return http.StatusUnauthorized
-	case codes.Unknown:
-		if desc := st.Message(); desc == "bad username or password" {
-			return http.StatusUnauthorized
+// percentEncode the string as gRPC does with the grpc-message:
+// bytes outside of the printable ASCII range, and the '%' are %XX-encoded.
+func percentEncode(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		} else {
+			buf.WriteByte(c)
 		}
 	}
-	return http.StatusInternalServerError
+	return buf.String()
 }
 
 func limitWidth(b []byte, width int) string {
@@ -0,0 +1,358 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/klauspost/compress/gzhttp"
+	"google.golang.org/grpc/codes"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	// JSONRPCServerError is the base of the gRPC status codes: a gRPC error is returned with JSONRPCServerError - code.
+	JSONRPCServerError = -32000
+)
+
+// JSONRPCHandler serves the Client's functions as a JSON-RPC 2.0 endpoint,
+// with batch requests and notifications.
+//
+// The params is the input object, or an array of input objects, which are streamed to the function.
+//
+// The result is the array of the received parts for server-streaming and bidirectional functions,
+// the received part for the others - or, if the streaming kind of the function is unknown,
+// an array iff more than one part is received.
+// If NotifyMethod is set, then each part is sent as a {"jsonrpc":"2.0","method":NotifyMethod,"params":{"id":id,"result":part}}
+// notification before the response (with null result), in an NDJSON stream.
+type JSONRPCHandler struct {
+	Client
+	*slog.Logger
+	GetLogger func(context.Context) *slog.Logger
+	// NotifyMethod is the method name of the notifications of the streamed parts.
+	NotifyMethod string
+	// JSON is the codec of the inputs and outputs.
+	JSON    JSONCodec
+	Timeout time.Duration
+}
+
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonrpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		ID     json.RawMessage `json:"id"`
+		Result any             `json:"result"`
+	} `json:"params"`
+}
+
+// JSONRPCError is the error object of the JSON-RPC 2.0 response.
+type JSONRPCError struct {
+	Data    any    `json:"data,omitempty"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (e *JSONRPCError) Error() string { return fmt.Sprintf("%d: %s", e.Code, e.Message) }
+
+var jsonNull = json.RawMessage("null")
+
+func newJSONRPCError(code int, err error) *JSONRPCError {
+	var je *JSONRPCError
+	if errors.As(err, &je) {
+		return je
+	}
+	st, isStatus := grpcStatus(err)
+	if code == 0 {
+		switch st.Code() {
+		case codes.OK:
+			code = JSONRPCInternalError
+		case codes.InvalidArgument:
+			code = JSONRPCInvalidParams
+		case codes.Unimplemented:
+			code = JSONRPCMethodNotFound
+		case codes.Internal:
+			code = JSONRPCInternalError
+		default:
+			code = JSONRPCServerError - int(st.Code())
+		}
+	}
+	e := JSONRPCError{Code: code, Message: err.Error()}
+	if isStatus {
+		e.Message = st.Message()
+		e.Data = struct {
+			Code    string            `json:"code"`
+			Details []json.RawMessage `json:"details,omitempty"`
+		}{Code: st.Code().String(), Details: statusDetails(st)}
+	}
+	return &e
+}
+
+func (h JSONRPCHandler) getLogger(ctx context.Context) *slog.Logger {
+	if h.GetLogger != nil {
+		if lgr := h.GetLogger(ctx); lgr != nil {
+			return lgr
+		}
+	}
+	return h.Logger
+}
+
+func (h JSONRPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gzhttp.GzipHandler(http.HandlerFunc(h.serveHTTP)).ServeHTTP(w, r)
+}
+func (h JSONRPCHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+	ctx := r.Context()
+	logger := h.getLogger(ctx)
+	if r.Method != "POST" {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if u, p, ok := r.BasicAuth(); ok {
+		logger.Info("basicAuth", "username", u)
+		ctx = WithBasicAuth(ctx, u, p)
+	}
+
+	br := bufio.NewReader(r.Body)
+	var batch bool
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			break
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+		br.UnreadByte()
+		batch = c == '['
+		break
+	}
+	var raws []json.RawMessage
+	var err error
+	if batch {
+		err = json.NewDecoder(br).Decode(&raws)
+	} else {
+		var raw json.RawMessage
+		if err = json.NewDecoder(br).Decode(&raw); err == nil {
+			raws = append(raws, raw)
+		}
+	}
+	enc := json.NewEncoder(w)
+	if err != nil || batch && len(raws) == 0 {
+		code := JSONRPCParseError
+		if err == nil {
+			code, err = JSONRPCInvalidRequest, errors.New("empty batch")
+		}
+		logger.Error("decode", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		enc.Encode(jsonrpcResponse{JSONRPC: "2.0", ID: jsonNull, Error: newJSONRPCError(code, err)})
+		return
+	}
+
+	var notify func(id json.RawMessage, part any) error
+	var wroteHeader bool
+	writeHeader := func() {
+		if !wroteHeader {
+			wroteHeader = true
+			if h.NotifyMethod != "" {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+			} else {
+				w.Header().Set("Content-Type", "application/json")
+			}
+			w.WriteHeader(200)
+		}
+	}
+	if h.NotifyMethod != "" {
+		rc := http.NewResponseController(w)
+		notify = func(id json.RawMessage, part any) error {
+			writeHeader()
+			n := jsonrpcNotification{JSONRPC: "2.0", Method: h.NotifyMethod}
+			n.Params.ID, n.Params.Result = id, h.JSON.value(part)
+			if err := enc.Encode(n); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+	}
+
+	resps := make([]jsonrpcResponse, 0, len(raws))
+	for _, raw := range raws {
+		var req jsonrpcRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			resps = append(resps, jsonrpcResponse{JSONRPC: "2.0", ID: jsonNull, Error: newJSONRPCError(JSONRPCInvalidRequest, err)})
+			continue
+		}
+		resp := jsonrpcResponse{JSONRPC: "2.0", ID: req.ID}
+		if len(resp.ID) == 0 {
+			resp.ID = jsonNull
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			resp.Error = newJSONRPCError(JSONRPCInvalidRequest, fmt.Errorf("wanted jsonrpc=2.0 and method, got %q and %q", req.JSONRPC, req.Method))
+			resps = append(resps, resp)
+			continue
+		}
+		var result any
+		if result, err = h.call(ctx, req, notify); err != nil {
+			logger.Error("call", "method", req.Method, "id", req.ID, "error", err)
+			resp.Error = newJSONRPCError(0, err)
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Error = newJSONRPCError(JSONRPCInternalError, err)
+		}
+		if len(req.ID) == 0 { // notification
+			continue
+		}
+		resps = append(resps, resp)
+	}
+
+	if len(resps) == 0 {
+		if !wroteHeader {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+	writeHeader()
+	if batch && h.NotifyMethod == "" {
+		err = enc.Encode(resps)
+	} else {
+		for _, resp := range resps {
+			if err = enc.Encode(resp); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		logger.Error("encode", "error", err)
+	}
+}
+
+// call the function with the params as inputs, and return the result.
+//
+// If notify is not nil, then each part is given to notify, and the result is nil.
+func (h JSONRPCHandler) call(ctx context.Context, req jsonrpcRequest, notify func(json.RawMessage, any) error) (any, error) {
+	logger := h.getLogger(ctx)
+	if h.Input(req.Method) == nil {
+		return nil, &JSONRPCError{Code: JSONRPCMethodNotFound, Message: fmt.Sprintf("no unmarshaler for %q: %v", req.Method, ErrNotFound)}
+	}
+	params := bytes.TrimSpace(req.Params)
+	var raws []json.RawMessage
+	if len(params) != 0 && params[0] == '[' {
+		if err := json.Unmarshal(params, &raws); err != nil {
+			return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: err.Error()}
+		}
+	} else if len(params) != 0 && !bytes.Equal(params, jsonNull) {
+		raws = append(raws, json.RawMessage(params))
+	}
+	inputs := make([]any, 0, max(1, len(raws)))
+	for _, raw := range raws {
+		inp := h.Input(req.Method)
+		if err := h.JSON.Unmarshal(raw, inp); err != nil {
+			return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: err.Error()}
+		}
+		inputs = append(inputs, inp)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, h.Input(req.Method))
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := h.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+	logger.Info("call", "method", req.Method, "id", req.ID, "inputs", len(inputs))
+
+	var recv Receiver
+	if len(inputs) == 1 {
+		var err error
+		if recv, err = h.Call(req.Method, ctx, inputs[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		var stop func()
+		var err error
+		ctx, recv, stop, err = callSending(ctx, nil, h.Client, req.Method, func(stream Sender) error {
+			for _, inp := range inputs {
+				if err := stream.Send(inp); err != nil {
+					return fmt.Errorf("send: %w", err)
+				}
+			}
+			return stream.CloseSend()
+		}, logger)
+		defer stop()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var parts []any
+	for part, err := range All(recv) {
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil && !errors.Is(err, cause) {
+				err = fmt.Errorf("%w: %w", cause, err)
+			}
+			return nil, err
+		}
+		if notify == nil {
+			parts = append(parts, h.JSON.value(part))
+		} else if len(req.ID) != 0 {
+			if err := notify(req.ID, part); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if notify != nil {
+		return nil, nil
+	}
+	switch describeMethod(h.Client, req.Method, inputs[0]).streaming {
+	case StreamingServer, StreamingBidi:
+		if parts == nil {
+			parts = []any{}
+		}
+		return parts, nil
+	case StreamingUnary, StreamingClient:
+		if len(parts) == 0 {
+			return nil, nil
+		}
+		return parts[0], nil
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return parts, nil
+}
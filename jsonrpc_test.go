@@ -1,17 +1,27 @@
-// Copyright 2017, 2021 Tamás Gulácsi.
+// Copyright 2017, 2026 Tamás Gulácsi.
 //
 // SPDX-License-Identifier: Apache-2.0
 
 package grpcer_test
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	json "encoding/json"
 
+	"github.com/UNO-SOFT/grpcer"
+	"github.com/UNO-SOFT/zlog/v2"
 	"github.com/tgulacsi/oracall/custom"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 func TestDateTime(t *testing.T) {
@@ -49,3 +59,293 @@ func TestDateTime(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type testInput struct {
+	A int `json:"a"`
+}
+type testOutput struct {
+	Sum   int
+	Count int
+}
+
+// testClient's Sum adds the inputs' A, Count streams A outputs, Fail fails after streaming A outputs.
+type testClient struct{}
+
+func (testClient) List() []string { return []string{"Count", "Fail", "Sum"} }
+func (testClient) Input(name string) any {
+	switch name {
+	case "Count", "Fail", "Sum":
+		return new(testInput)
+	}
+	return nil
+}
+func (c testClient) Call(name string, ctx context.Context, input any, opts ...grpc.CallOption) (grpcer.Receiver, error) {
+	inp := input.(*testInput)
+	switch name {
+	case "Sum":
+		return &testRecv{parts: []any{testOutput{Sum: inp.A, Count: 1}}}, nil
+	case "Count", "Fail":
+		parts := make([]any, 0, inp.A)
+		for i := range inp.A {
+			parts = append(parts, testOutput{Sum: i, Count: i + 1})
+		}
+		recv := &testRecv{parts: parts}
+		if name == "Fail" {
+			recv.err = status.Error(codes.Unavailable, "failed")
+		}
+		return recv, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "%s: %v", name, grpcer.ErrNotFound)
+}
+func (c testClient) CallStream(name string, ctx context.Context, opts ...grpc.CallOption) (grpcer.Stream, error) {
+	if name != "Sum" {
+		return grpcer.NewUnaryStream(ctx, func(input any) (grpcer.Receiver, error) {
+			return c.Call(name, ctx, input, opts...)
+		}), nil
+	}
+	return &sumStream{ctx: ctx, inputs: make(chan int)}, nil
+}
+
+// streamingClient is a testClient which describes the streaming kind of its methods by MethodInfo.
+type streamingClient struct{ testClient }
+
+func (streamingClient) Tags(name string) []string     { return nil }
+func (streamingClient) FullMethod(name string) string { return "/test.Test/" + name }
+func (streamingClient) Streaming(name string) (bool, bool) {
+	return name == "Sum", name == "Count" || name == "Fail"
+}
+func (streamingClient) Descriptors(name string) (input, output protoreflect.MessageDescriptor) {
+	return nil, nil
+}
+func (streamingClient) Comments(name string) string { return "" }
+
+var _ grpcer.MethodInfo = streamingClient{}
+
+type testRecv struct {
+	parts []any
+	err   error
+}
+
+func (r *testRecv) Recv() (any, error) {
+	if len(r.parts) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, io.EOF
+	}
+	p := r.parts[0]
+	r.parts = r.parts[1:]
+	return p, nil
+}
+
+// sumStream sums the inputs, and refuses the negative ones.
+type sumStream struct {
+	ctx    context.Context
+	inputs chan int
+	done   bool
+}
+
+func (s *sumStream) Send(input any) error {
+	a := input.(*testInput).A
+	if a < 0 {
+		return status.Errorf(codes.InvalidArgument, "negative input %d", a)
+	}
+	s.inputs <- a
+	return nil
+}
+func (s *sumStream) CloseSend() error { close(s.inputs); return nil }
+func (s *sumStream) Recv() (any, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	var out testOutput
+	for a := range s.inputs {
+		out.Sum += a
+		out.Count++
+	}
+	s.done = true
+	if err := s.ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return out, nil
+}
+
+func TestJSONHandlerStreamedInput(t *testing.T) {
+	h := grpcer.JSONHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()}
+	hInfo := h
+	hInfo.Client = streamingClient{}
+	for tN, tC := range map[string]struct {
+		Handler                 grpcer.JSONHandler
+		ContentType, Body, Want string
+	}{
+		"single": {Handler: h, Body: `{"a":3}`, Want: `{"Sum":3,"Count":1}`},
+		"array":  {Handler: h, Body: ` [{"a":1}, {"a":2},{"A":"3"}]`, Want: `{"Sum":6,"Count":3}`},
+		"ndjson": {Handler: h, ContentType: "application/x-ndjson", Body: "{\"a\":1}\n{\"a\":2}\n", Want: `{"Sum":3,"Count":2}`},
+		// the client streaming method takes the stream, without the content type
+		"info-single": {Handler: hInfo, Body: `{"a":3}`, Want: `{"Sum":3,"Count":1}`},
+		"info-ndjson": {Handler: hInfo, Body: "{\"a\":1}\n{\"a\":2}\n", Want: `{"Sum":3,"Count":2}`},
+	} {
+		h := tC.Handler
+		r := httptest.NewRequest("POST", "/Sum", strings.NewReader(tC.Body))
+		if tC.ContentType != "" {
+			r.Header.Set("Content-Type", tC.ContentType)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Errorf("%s: got %d: %s", tN, w.Code, w.Body.String())
+			continue
+		}
+		if got := strings.TrimSpace(w.Body.String()); got != tC.Want {
+			t.Errorf("%s: got %s, wanted %s", tN, got, tC.Want)
+		}
+	}
+}
+
+func TestJSONHandlerBadInput(t *testing.T) {
+	h := grpcer.JSONHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()}
+	for tN, tC := range map[string]struct {
+		ContentType, Body string
+	}{
+		"truncated": {Body: `[{"a": 1`},
+		"ndjson":    {ContentType: "application/x-ndjson", Body: "{\"a\":"},
+	} {
+		r := httptest.NewRequest("POST", "/Count", strings.NewReader(tC.Body))
+		if tC.ContentType != "" {
+			r.Header.Set("Content-Type", tC.ContentType)
+		}
+		w := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() { defer close(done); h.ServeHTTP(w, r) }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: handler hangs", tN)
+		}
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d: %s, wanted 400", tN, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestJSONHandlerSSE(t *testing.T) {
+	h := grpcer.JSONHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()}
+	for tN, tC := range map[string]struct {
+		URL, Accept, Want string
+	}{
+		"query":  {URL: "/Count?format=sse", Want: "data: {\"Sum\":0,\"Count\":1}\n\ndata: {\"Sum\":1,\"Count\":2}\n\nevent: end\ndata: {}\n\n"},
+		"accept": {URL: "/Count", Accept: "text/event-stream", Want: "data: {\"Sum\":0,\"Count\":1}\n\ndata: {\"Sum\":1,\"Count\":2}\n\nevent: end\ndata: {}\n\n"},
+		"error":  {URL: "/Fail?format=sse", Want: "data: {\"Sum\":0,\"Count\":1}\n\ndata: {\"Sum\":1,\"Count\":2}\n\nevent: error\ndata: {\"Error\":\"rpc error: code = Unavailable desc = failed\",\"Code\":\"Unavailable\",\"Message\":\"failed\"}\n\n"},
+	} {
+		r := httptest.NewRequest("POST", tC.URL, strings.NewReader(`{"a":2}`))
+		if tC.Accept != "" {
+			r.Header.Set("Accept", tC.Accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("%s: got Content-Type %q", tN, ct)
+		}
+		if got := w.Body.String(); got != tC.Want {
+			t.Errorf("%s: got %q, wanted %q", tN, got, tC.Want)
+		}
+	}
+}
+
+func TestJSONRPCHandler(t *testing.T) {
+	for tN, tC := range map[string]struct {
+		Notify, Body, Want string
+	}{
+		"single": {Body: `{"jsonrpc":"2.0","method":"Sum","params":{"a":3},"id":1}`,
+			Want: `{"jsonrpc":"2.0","result":{"Sum":3,"Count":1},"id":1}`},
+		"stream": {Body: `{"jsonrpc":"2.0","method":"Count","params":{"a":2},"id":"x"}`,
+			Want: `{"jsonrpc":"2.0","result":[{"Sum":0,"Count":1},{"Sum":1,"Count":2}],"id":"x"}`},
+		"inputs": {Body: `{"jsonrpc":"2.0","method":"Sum","params":[{"a":1},{"a":2}],"id":2}`,
+			Want: `{"jsonrpc":"2.0","result":{"Sum":3,"Count":2},"id":2}`},
+		"sendError": {Body: `{"jsonrpc":"2.0","method":"Sum","params":[{"a":1},{"a":-2}],"id":2}`,
+			Want: `{"jsonrpc":"2.0","error":{"data":{"code":"InvalidArgument"},"message":"negative input -2","code":-32602},"id":2}`},
+		"notification": {Body: `{"jsonrpc":"2.0","method":"Sum","params":{"a":3}}`},
+		"parseError": {Body: `{"jsonrpc":"2.0",`,
+			Want: `{"jsonrpc":"2.0","error":{"message":"unexpected EOF","code":-32700},"id":null}`},
+		"batch": {Body: `[{"jsonrpc":"2.0","method":"Sum","params":{"a":3},"id":1},
+			{"jsonrpc":"2.0","method":"Sum","params":{"a":3}},
+			{"jsonrpc":"2.0","method":"Unknown","id":2},
+			{"jsonrpc":"2.0","method":"Fail","params":{"a":1},"id":3},
+			{"method":"Sum","id":4}]`,
+			Want: `[{"jsonrpc":"2.0","result":{"Sum":3,"Count":1},"id":1},` +
+				`{"jsonrpc":"2.0","error":{"message":"no unmarshaler for \"Unknown\": not found","code":-32601},"id":2},` +
+				`{"jsonrpc":"2.0","error":{"data":{"code":"Unavailable"},"message":"failed","code":-32014},"id":3},` +
+				`{"jsonrpc":"2.0","error":{"message":"wanted jsonrpc=2.0 and method, got \"\" and \"Sum\"","code":-32600},"id":4}]`},
+		"notify": {Notify: "part", Body: `{"jsonrpc":"2.0","method":"Count","params":{"a":2},"id":1}`,
+			Want: `{"jsonrpc":"2.0","method":"part","params":{"id":1,"result":{"Sum":0,"Count":1}}}` + "\n" +
+				`{"jsonrpc":"2.0","method":"part","params":{"id":1,"result":{"Sum":1,"Count":2}}}` + "\n" +
+				`{"jsonrpc":"2.0","result":null,"id":1}`},
+	} {
+		h := grpcer.JSONRPCHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog(), NotifyMethod: tC.Notify}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(tC.Body)))
+		if got := strings.TrimSpace(w.Body.String()); got != tC.Want {
+			t.Errorf("%s: got\n%s\nwanted\n%s", tN, got, tC.Want)
+		}
+		if tC.Want == "" && w.Code != 204 {
+			t.Errorf("%s: got %d, wanted 204", tN, w.Code)
+		}
+	}
+
+	// the shape of the result follows the streaming kind
+	h := grpcer.JSONRPCHandler{Client: streamingClient{}, Logger: zlog.NewT(t).SLog()}
+	for body, want := range map[string]string{
+		`{"jsonrpc":"2.0","method":"Count","params":{"a":1},"id":1}`:         `{"jsonrpc":"2.0","result":[{"Sum":0,"Count":1}],"id":1}`,
+		`{"jsonrpc":"2.0","method":"Sum","params":[{"a":1},{"a":2}],"id":2}`: `{"jsonrpc":"2.0","result":{"Sum":3,"Count":2},"id":2}`,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		if got := strings.TrimSpace(w.Body.String()); got != want {
+			t.Errorf("got\n%s\nwanted\n%s", got, want)
+		}
+	}
+}
+
+func TestJSONHandlerTrailer(t *testing.T) {
+	srv := httptest.NewServer(grpcer.JSONHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()})
+	defer srv.Close()
+	for tN, tC := range map[string]struct {
+		URL, Status, Want string
+		Gzip              bool
+	}{
+		"ok": {URL: "/Count", Status: "0",
+			Want: `{"Sum":0,"Count":1}` + "\n" + `{"Sum":1,"Count":2}`},
+		"error": {URL: "/Fail", Status: "14",
+			Want: `{"Sum":0,"Count":1}` + "\n" + `{"Sum":1,"Count":2}` + "\n" +
+				`{"Error":"rpc error: code = Unavailable desc = failed","Code":"Unavailable","Message":"failed"}`},
+		"gzipError": {URL: "/Fail", Status: "14", Gzip: true,
+			Want: `{"Sum":0,"Count":1}` + "\n" + `{"Sum":1,"Count":2}` + "\n" +
+				`{"Error":"rpc error: code = Unavailable desc = failed","Code":"Unavailable","Message":"failed"}`},
+		"mergeError": {URL: "/Fail?merge=1", Status: "14",
+			Want: `{"Sum":0,"Count":1}` + "\n" + `{"Sum":1,"Count":2}` + "\n" +
+				`{"Error":"rpc error: code = Unavailable desc = failed","Code":"Unavailable","Message":"failed"}`},
+	} {
+		req, err := http.NewRequest("POST", srv.URL+tC.URL, strings.NewReader(`{"a":2}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tC.Gzip {
+			req.Header.Set("Accept-Encoding", "identity")
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(b)); got != tC.Want {
+			t.Errorf("%s: got\n%s\nwanted\n%s", tN, got, tC.Want)
+		}
+		if got := resp.Trailer.Get("Grpc-Status"); got != tC.Status {
+			t.Errorf("%s: got status %q, wanted %q (%+v)", tN, got, tC.Status, resp.Trailer)
+		}
+	}
+}
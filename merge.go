@@ -6,193 +6,617 @@ package grpcer
 
 import (
 	"bytes"
+	"cmp"
 	json "encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/klauspost/compress/zstd"
-	"log/slog"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-var (
-	errNewField  = errors.New("new field")
-	errWrongType = errors.New("wrong type")
+// MergePolicy is the policy of merging the scalar fields of the streamed parts.
+type MergePolicy uint8
+
+const (
+	// MergeFirstWins keeps the first non-null value of a scalar field.
+	MergeFirstWins = MergePolicy(iota)
+	// MergeLastWins keeps the last non-null value of a scalar field.
+	MergeLastWins
 )
 
-func mergeStreams(w io.Writer, first any, recv interface{ Recv() (any, error) }, logger *slog.Logger) error {
-	slice, notSlice := SliceFields(first, "json")
-	if len(slice) == 0 {
-		var err error
-		part := first
-		enc := json.NewEncoder(w)
-		for {
-			if err := enc.Encode(part); err != nil {
-				logger.Error("encode", part, "error", err)
-				return fmt.Errorf("encode part: %w", err)
-			}
+// mergeConfig is the configuration of mergeStreams.
+type mergeConfig struct {
+	// TempDir is the directory of the spilled buffers.
+	TempDir string
+	// SpillLimit is the in-memory limit of each buffer (see SpillBuffer.Limit).
+	SpillLimit int
+	Codec      JSONCodec
+	Policy     MergePolicy
+}
+
+// newBuffer returns a new SpillBuffer, configured by the mergeConfig.
+func (cfg mergeConfig) newBuffer(name string) *SpillBuffer {
+	return NewSpillBuffer(cfg.TempDir, name, cfg.SpillLimit)
+}
 
-			part, err = recv.Recv()
-			if err != nil {
-				if !errors.Is(err, io.EOF) {
-					logger.Error("recv", "error", err)
+// mergeStreams merges the parts into one JSON object, and writes it to w.
+//
+// The parts are merged deeply: arrays are concatenated, objects (nested messages and maps)
+// are merged key by key, and the scalars are merged according to the policy.
+// Fields appearing only in later parts are added; null means a missing value.
+// The arrays are collected in SpillBuffers, the rest is kept in memory till the last part.
+// The parts of a plain struct type or a proto message are merged field by field,
+// following its cached mergePlan, others are encoded and parsed.
+// The fields are written in the declaration order of the first part's type (see keyOrder).
+//
+// If the first part has no arrays, or the parts cannot be merged (a field changes its type),
+// the parts are written as is, one per line.
+// As all the parts are buffered in a SpillBuffer, this is decided before writing anything.
+//
+// If receiving fails after the first part, the object of the parts merged so far is written,
+// and the error is returned without writing it, as any key of the object could clash
+// with a field of the parts: the caller sends it in the trailers (see setStatusTrailer).
+func mergeStreams(w io.Writer, cfg mergeConfig, first any, recv Receiver, logger *slog.Logger) error {
+	codec := cfg.Codec
+	// fail writes the error as the only record, as nothing has been written yet
+	fail := func(err error) error {
+		logger.Error("merge", "error", err)
+		codec.Encode(w, NewErrorBody(err))
+		return err
+	}
+	m := merger{mergeConfig: cfg}
+	defer m.Close()
+	// The parts of the first part's type are merged by its plan, if it has a rigid one:
+	// then the merge cannot fail, and the parts need not be kept.
+	// Otherwise keep all the parts, to be able to send them as is if they cannot be merged.
+	firstType := reflect.TypeOf(first)
+	plan := m.planOf(firstType)
+	var root mergeNode
+	var b []byte
+	var err error
+	var isArray bool
+	if plan != nil {
+		if err = m.mergeValue(&root, "", reflect.ValueOf(first), plan); err != nil {
+			return fail(fmt.Errorf("merge part: %w", err))
+		}
+		isArray = root.hasArray()
+	} else {
+		if b, err = codec.Marshal(first); err != nil {
+			return fail(fmt.Errorf("encode part: %w", err))
+		}
+		isArray = hasArray(b)
+	}
+	if !isArray {
+		if b == nil {
+			err = codec.Encode(w, first)
+		} else {
+			_, err = w.Write(append(b, '\n'))
+		}
+		if err != nil {
+			return err
+		}
+		return encodeParts(w, codec, recv, logger)
+	}
+
+	var parts *SpillBuffer
+	if plan == nil {
+		parts = cfg.newBuffer("merge-parts-*.json.zst")
+		defer parts.Close()
+	}
+	var recvErr error
+	fallback := func(err error) error {
+		if parts == nil {
+			// close the object, returning the error
+			logger.Error("merge", "error", err)
+			recvErr = err
+			return nil
+		}
+		logger.Warn("merge failed, sending the parts as is", "error", err)
+		m.Close()
+		rc, err := parts.GetReader()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		return encodeParts(w, codec, recv, logger)
+	}
+
+	// the first part is merged already by the plan
+	seq := All(recv)
+	if plan == nil {
+		seq = prepend(first, seq)
+	}
+	for part, err := range seq {
+		if err != nil {
+			logger.Error("recv", "error", err)
+			recvErr = fmt.Errorf("recv: %w", err)
+			break
+		}
+		if plan != nil && reflect.TypeOf(part) == firstType {
+			err = m.mergeValue(&root, "", reflect.ValueOf(part), plan)
+		} else {
+			// the first part is encoded already
+			if b == nil {
+				if b, err = codec.Marshal(part); err != nil {
+					return fail(fmt.Errorf("encode part: %w", err))
+				}
+				logger.Debug("encode", "part", limitWidth(b, 256))
+			}
+			if parts != nil {
+				if _, err = parts.Write(append(b, '\n')); err != nil {
+					return fail(err)
 				}
-				break
 			}
+			err = m.merge(&root, "", b)
+			b = nil
 		}
-		logger.Debug("slice", "len", len(slice))
-		return nil
+		if err != nil {
+			if err = fallback(err); err != nil || recvErr == nil {
+				return err
+			}
+			break
+		}
+	}
+
+	w.Write([]byte{'{'})
+	if _, err = m.writeFields(w, &root, codec.typeOrder(reflect.TypeOf(first))); err != nil {
+		logger.Error("write merged", "error", err)
+		if recvErr == nil {
+			recvErr = err
+		}
+	}
+	_, err = w.Write([]byte{'}', '\n'})
+	if recvErr != nil {
+		return recvErr
 	}
+	return err
+}
 
-	names := make(map[string]bool, len(slice)+len(notSlice))
+// encodeParts writes the received parts as is, one per line, and the error as the last line.
+func encodeParts(w io.Writer, codec JSONCodec, recv Receiver, logger *slog.Logger) error {
+	for part, err := range All(recv) {
+		if err != nil {
+			logger.Error("recv", "error", err)
+			codec.Encode(w, NewErrorBody(err))
+			return fmt.Errorf("recv: %w", err)
+		}
+		if err := codec.Encode(w, part); err != nil {
+			logger.Error("encode", part, "error", err)
+			return fmt.Errorf("encode part: %w", err)
+		}
+	}
+	return nil
+}
 
-	buf := bufPool.Get().(*bytes.Buffer)
-	defer func() {
-		buf.Reset()
-		bufPool.Put(buf)
-	}()
-	jenc := json.NewEncoder(buf)
+// child returns the child of the object node with the key, adding it if it is new.
+func (nd *mergeNode) child(k string) *mergeNode {
+	if child := nd.children[k]; child != nil {
+		return child
+	}
+	if nd.children == nil {
+		nd.children = make(map[string]*mergeNode)
+	}
+	child := new(mergeNode)
+	nd.children[k] = child
+	nd.keys = append(nd.keys, k)
+	return child
+}
 
-	w.Write([]byte("{"))
-	for _, f := range notSlice {
-		buf.Reset()
-		jenc.Encode(f.TagName)
-		w.Write(bytes.TrimSpace(buf.Bytes()))
+// setKind sets the kind of the node, or returns ErrWrongType if it is of another kind.
+func (nd *mergeNode) setKind(name string, kind byte) error {
+	if nd.kind == 0 {
+		nd.kind = kind
+	} else if nd.kind != kind {
+		return fmt.Errorf("%s is %q, not %q: %w", name, nd.kind, kind, ErrWrongType)
+	}
+	return nil
+}
 
-		w.Write([]byte{':'})
-		buf.Reset()
-		jenc.Encode(f.Value)
-		w.Write(bytes.TrimSpace(buf.Bytes()))
-		w.Write([]byte{','})
-
-		names[f.Name] = false
-	}
-	buf.Reset()
-	jenc.Encode(slice[0].TagName)
-	w.Write(bytes.TrimSpace(buf.Bytes()))
-	w.Write([]byte(":"))
-
-	buf.Reset()
-	jenc.Encode(slice[0].Value)
-	w.Write(bytes.TrimSuffix(bytes.TrimSpace(buf.Bytes()), []byte{']'}))
-
-	names[slice[0].Name] = true
-	files := make(map[string]*TempFile, len(slice)-1)
-	openFile := func(f Field) error {
-		fh, err := NewTempFile("", "merge-"+f.Name+"-*.json.zst")
-		if err != nil {
-			logger.Error("newTempFile", "name", f.Name, "error", err)
-			return fmt.Errorf("%s: %w", f.Name, err)
+// hasArray reports whether the node is an array, or has one in its objects.
+func (nd *mergeNode) hasArray() bool {
+	if nd.kind == '[' {
+		return true
+	}
+	for _, child := range nd.children {
+		if child.hasArray() {
+			return true
 		}
-		files[f.Name] = fh
-		buf.Reset()
-		jenc.Encode(f.TagName)
-		fh.Write(bytes.TrimSpace(buf.Bytes()))
-		io.WriteString(fh, ":[")
+	}
+	return false
+}
 
-		buf.Reset()
-		jenc.Encode(f.Value)
-		fh.Write(trimSqBrs(buf.Bytes()))
+// mergeNode is a value of the merged object.
+type mergeNode struct {
+	children map[string]*mergeNode
+	array    *SpillBuffer
+	raw      json.RawMessage
+	keys     []string
+	// kind is '{' for objects, '[' for arrays, 's' for scalars and 0 for null.
+	kind byte
+	// empty is true while the array has no elements.
+	empty bool
+}
+
+type merger struct {
+	enc     *json.Encoder // of buf
+	files   []*SpillBuffer
+	scratch []byte
+	buf     bytes.Buffer
+	mergeConfig
+}
 
-		names[f.Name] = true
+// Close the buffers of the arrays.
+func (m *merger) Close() error {
+	var firstErr error
+	for _, fh := range m.files {
+		if err := fh.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	m.files = nil
+	return firstErr
+}
+
+// merge the JSON value into the node.
+func (m *merger) merge(nd *mergeNode, name string, raw json.RawMessage) error {
+	kind := jsonKind(raw)
+	if kind == 0 {
 		return nil
 	}
-	defer func() {
-		for nm, fh := range files {
-			fh.Close()
-			delete(files, nm)
+	if err := nd.setKind(name, kind); err != nil {
+		return err
+	}
+	switch kind {
+	case '{':
+		keys, values, err := objectFields(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for i, k := range keys {
+			child := nd.child(k)
+			nm := k
+			if name != "" {
+				nm = name + "." + k
+			}
+			if err = m.merge(child, nm, values[i]); err != nil {
+				return err
+			}
+		}
+	case '[':
+		return m.appendArray(nd, name, trimSqBrs(raw))
+	default:
+		if nd.raw == nil || m.Policy == MergeLastWins {
+			nd.raw = raw
 		}
-	}()
+	}
+	return nil
+}
 
-	for _, f := range slice[1:] {
-		if err := openFile(f); err != nil {
-			return err
+// appendArray appends the elements (comma separated, without the brackets) to the array node.
+func (m *merger) appendArray(nd *mergeNode, name string, elems []byte) error {
+	if err := nd.setKind(name, '['); err != nil {
+		return err
+	}
+	if nd.array == nil {
+		nd.array, nd.empty = m.newBuffer("merge-*.json.zst"), true
+		m.files = append(m.files, nd.array)
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+	if !nd.empty {
+		if _, err := nd.array.Write([]byte{','}); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	nd.empty = false
+	if _, err := nd.array.Write(elems); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// writeFields writes the fields of the object node in the given order, returning their number and the first error.
+//
+// The values which cannot be read back are written as null, to keep the output well-formed.
+func (m *merger) writeFields(w io.Writer, nd *mergeNode, order *keyOrder) (int, error) {
+	keys, orders := order.sort(nd.keys)
+	var firstErr error
+	for i, k := range keys {
+		if i != 0 {
+			w.Write([]byte{','})
+		}
+		b, _ := json.Marshal(k)
+		w.Write(b)
+		w.Write([]byte{':'})
+		if err := m.write(w, nd.children[k], orders[i]); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", k, err)
 		}
 	}
+	return len(keys), firstErr
+}
 
-	var part any
-	var err error
-	for {
-		part, err = recv.Recv()
+// write the node as JSON.
+func (m *merger) write(w io.Writer, nd *mergeNode, order *keyOrder) error {
+	switch nd.kind {
+	case 0:
+		_, err := w.Write([]byte("null"))
+		return err
+	case '{':
+		w.Write([]byte{'{'})
+		_, err := m.writeFields(w, nd, order)
+		if _, wErr := w.Write([]byte{'}'}); err == nil {
+			err = wErr
+		}
+		return err
+	case '[':
+		rc, err := nd.array.GetReader()
 		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				logger.Error("recv", "error", err)
+			w.Write([]byte("null"))
+			return err
+		}
+		defer rc.Close()
+		w.Write([]byte{'['})
+		if _, err = io.Copy(w, rc); err != nil {
+			return err
+		}
+		_, err = w.Write([]byte{']'})
+		return err
+	default:
+		_, err := w.Write(nd.raw)
+		return err
+	}
+}
+
+// keyOrder is the order of the keys of a JSON object, as encoding/json or protojson encodes it:
+// the declaration order of the fields of structs and messages, and sorted keys for maps.
+type keyOrder struct {
+	// fields of the struct or message, nil for maps.
+	fields map[string]fieldOrder
+	// elem returns the keyOrder of the map's values.
+	elem func() *keyOrder
+}
+
+type fieldOrder struct {
+	order func() *keyOrder
+	index int
+}
+
+// sort returns the keys in order, with the keyOrders of their values.
+//
+// Unknown keys come last, in their original order; a nil keyOrder keeps the original order.
+func (o *keyOrder) sort(keys []string) ([]string, []*keyOrder) {
+	orders := make([]*keyOrder, len(keys))
+	if o == nil {
+		return keys, orders
+	}
+	keys = slices.Clone(keys)
+	if o.fields == nil {
+		slices.Sort(keys)
+		if o.elem != nil {
+			elem := o.elem()
+			for i := range orders {
+				orders[i] = elem
 			}
-			break
 		}
-		buf.Reset()
-		jenc.Encode(part)
-		logger.Debug("encode", "part", limitWidth(buf.Bytes(), 256))
+		return keys, orders
+	}
+	index := func(k string) int {
+		if f, ok := o.fields[k]; ok {
+			return f.index
+		}
+		return len(o.fields)
+	}
+	slices.SortStableFunc(keys, func(a, b string) int { return index(a) - index(b) })
+	for i, k := range keys {
+		if f, ok := o.fields[k]; ok && f.order != nil {
+			orders[i] = f.order()
+		}
+	}
+	return keys, orders
+}
+
+// typeOrder returns the keyOrder of the JSON encoding of the type, nil if not an object.
+func (c JSONCodec) typeOrder(t reflect.Type) *keyOrder {
+	if t == nil {
+		return nil
+	}
+	if t.Implements(protoMessageType) {
+		if t.Kind() != reflect.Pointer {
+			return nil
+		}
+		return c.messageOrder(reflect.Zero(t).Interface().(proto.Message).ProtoReflect().Descriptor())
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return &keyOrder{elem: func() *keyOrder { return c.typeOrder(t.Elem()) }}
+	case reflect.Struct:
+		o := keyOrder{fields: make(map[string]fieldOrder)}
+		c.addStructFields(&o, t)
+		return &o
+	}
+	return nil
+}
+
+// addStructFields adds the fields of the struct as encoding/json encodes them (see jsonFields).
+func (c JSONCodec) addStructFields(o *keyOrder, t reflect.Type) {
+	for _, f := range jsonFields(t) {
+		ft := f.Type
+		o.fields[f.name] = fieldOrder{
+			index: len(o.fields),
+			order: func() *keyOrder { return c.typeOrder(ft) },
+		}
+	}
+}
+
+// jsonField is a field of a struct as encoding/json encodes it.
+// Its Index is the index sequence from the outer struct.
+type jsonField struct {
+	name, opts string
+	reflect.StructField
+	tagged bool
+}
 
-		S, nS := SliceFields(part, "json")
-		for _, f := range S {
-			if isSlice, ok := names[f.Name]; !ok {
-				if err = openFile(f); err != nil {
-					break
+// jsonFields returns the fields of the struct type as encoding/json encodes them, in its order:
+// skipping the "-" tagged and unexported ones, and promoting the fields of the embedded structs.
+// Of the fields with the same name the dominant one is kept (see dominantField).
+func jsonFields(t reflect.Type) []jsonField {
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	var fields []jsonField
+	visited := make(map[reflect.Type]bool)
+	for next := []embedded{{t: t}}; len(next) != 0; {
+		current := next
+		next = nil
+		for _, e := range current {
+			if visited[e.t] {
+				continue
+			}
+			for i := range e.t.NumField() {
+				sf := e.t.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !sf.IsExported() && (!sf.Anonymous || ft.Kind() != reflect.Struct) {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
 				}
-				//err = fmt.Errorf("%s: %w", f.Name, errNewField)
-				//break
-			} else if !isSlice {
-				err = fmt.Errorf("%s not slice: %w", f.Name, errWrongType)
-				break
+				name, opts, _ := strings.Cut(tag, ",")
+				sf.Index = append(e.index[:len(e.index):len(e.index)], i)
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{t: ft, index: sf.Index})
+					continue
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				f := jsonField{name: name, opts: opts, StructField: sf, tagged: name != ""}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				fields = append(fields, f)
 			}
 		}
-		for _, f := range nS {
-			if isSlice, ok := names[f.Name]; !ok {
-				err = fmt.Errorf("%s: %w", f.Name, errNewField)
-				break
-			} else if isSlice {
-				err = fmt.Errorf("%s slice: %w", f.Name, errWrongType)
-				break
+		// the same type embedded twice at the same depth conflicts with itself
+		for _, e := range current {
+			visited[e.t] = true
+		}
+	}
+
+	slices.SortFunc(fields, func(a, b jsonField) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(a.Index), len(b.Index)); c != 0 {
+			return c
+		}
+		if a.tagged != b.tagged {
+			if a.tagged {
+				return -1
 			}
+			return 1
 		}
-		if len(S) == 0 {
-			break
+		return slices.Compare(a.Index, b.Index)
+	})
+	out := fields[:0]
+	for i, n := 0, 0; i < len(fields); i += n {
+		for n = 1; i+n < len(fields) && fields[i+n].name == fields[i].name; n++ {
 		}
-		if err != nil {
-			logger.Error("error", "error", err)
-			// nosemgrep: dgryski.semgrep-go.errtodo.err-todo
-			//TODO(tgulacsi): close the merge and send as is
-			return err
+		if f, ok := dominantField(fields[i : i+n]); ok {
+			out = append(out, f)
 		}
+	}
+	slices.SortFunc(out, func(a, b jsonField) int { return slices.Compare(a.Index, b.Index) })
+	return out
+}
 
-		if S[0].Name == slice[0].Name {
-			w.Write([]byte{','})
-			buf.Reset()
-			jenc.Encode(S[0].Value)
-			w.Write(trimSqBrs(buf.Bytes()))
-			S = S[1:]
-		}
-		for _, f := range S {
-			fh := files[f.Name]
-			if _, err := fh.Write([]byte{','}); err != nil {
-				logger.Error("write", "error", err)
+// dominantField returns the dominant one of the fields with the same name, sorted by depth, then tagged first:
+// the shallowest, or the tagged one of the shallowest.
+// If there is no such single field, the name is dropped, as by encoding/json.
+func dominantField(fields []jsonField) (jsonField, bool) {
+	if len(fields) > 1 && len(fields[0].Index) == len(fields[1].Index) && fields[0].tagged == fields[1].tagged {
+		return jsonField{}, false
+	}
+	return fields[0], true
+}
+
+// messageOrder returns the keyOrder of the protojson encoding of the message.
+func (c JSONCodec) messageOrder(md protoreflect.MessageDescriptor) *keyOrder {
+	if md.FullName().Parent() == "google.protobuf" {
+		if md.FullName() == "google.protobuf.Struct" {
+			return &keyOrder{}
+		}
+		return nil
+	}
+	fds := md.Fields()
+	o := keyOrder{fields: make(map[string]fieldOrder, fds.Len())}
+	for i := range fds.Len() {
+		fd := fds.Get(i)
+		var order func() *keyOrder
+		if fd.IsMap() {
+			order = func() *keyOrder {
+				o := keyOrder{}
+				if vd := fd.MapValue(); vd.Message() != nil {
+					o.elem = func() *keyOrder { return c.messageOrder(vd.Message()) }
+				}
+				return &o
 			}
-			buf.Reset()
-			jenc.Encode(f.Value)
-			fh.Write(trimSqBrs(buf.Bytes()))
+		} else if fd.Message() != nil {
+			order = func() *keyOrder { return c.messageOrder(fd.Message()) }
 		}
+		o.fields[c.fieldName(fd)] = fieldOrder{index: i, order: order}
 	}
-	w.Write([]byte("]"))
+	return &o
+}
 
-	for nm, fh := range files {
-		rc, err := fh.GetReader()
-		if err != nil {
-			logger.Error("GetReader", "name", nm, "error", err)
-			continue
+// jsonKind returns '{' for objects, '[' for arrays, 0 for null and 's' for other values.
+func jsonKind(raw json.RawMessage) byte {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] == 'n' {
+		return 0
+	}
+	if raw[0] == '{' || raw[0] == '[' {
+		return raw[0]
+	}
+	return 's'
+}
+
+// hasArray reports whether the JSON object has an array, directly or in its objects.
+func hasArray(raw json.RawMessage) bool {
+	if jsonKind(raw) != '{' {
+		return false
+	}
+	_, values, err := objectFields(raw)
+	if err != nil {
+		return false
+	}
+	for _, v := range values {
+		if k := jsonKind(v); k == '[' || k == '{' && hasArray(v) {
+			return true
 		}
-		w.Write([]byte{','})
-		io.Copy(w, rc)
-		rc.Close()
-		w.Write([]byte{']'})
-		fh.Close()
-		delete(files, nm)
 	}
-	w.Write([]byte{'}', '\n'})
-	return nil
+	return false
 }
 
 type Field struct {
@@ -284,7 +708,8 @@ func (f *TempFile) Close() error {
 		firstErr = zw.Close()
 	}
 	if file != nil {
-		if err := file.Close(); err != nil && firstErr == nil {
+		// the reader of GetReader may have closed it already
+		if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) && firstErr == nil {
 			firstErr = err
 		}
 		os.Remove(file.Name())
@@ -1,4 +1,4 @@
-// Copyright 2017, 2022 Tamás Gulácsi
+// Copyright 2017, 2026 Tamás Gulácsi
 //
 // SPDX-License-Identifier: Apache-2.0
 
@@ -8,31 +8,56 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/UNO-SOFT/zlog/v2"
 	"github.com/kylelemons/godebug/diff"
 	"github.com/tgulacsi/go/jsondiff"
 	"github.com/tgulacsi/go/stream"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/typepb"
 )
 
 func TestMerge(t *testing.T) {
 	buf := bufPool.Get().(*bytes.Buffer)
 	defer bufPool.Put(buf)
 	repComma := strings.NewReplacer(`",`, `",`+"\n")
+	type result struct {
+		Rows  []int          `json:"rows"`
+		Total int            `json:"total,omitempty"`
+		Sums  map[string]int `json:"sums,omitempty"`
+	}
+	type nested struct {
+		Name   string `json:"name,omitempty"`
+		Result result `json:"result"`
+	}
 	for tN, tC := range map[string]struct {
-		Want  string
-		Input []any
+		Err    error
+		Want   string
+		Input  []any
+		Policy MergePolicy
+		// AnyOrder allows the keys in any order.
+		AnyOrder bool
 	}{
 		"noSlice": {
 			Input: toIntf([]struct {
 				B string
 				A int
 			}{{A: 1, B: "x"}}),
-			Want: `{"A":1,"B":"x"}`,
+			Want: `{"B":"x","A":1}` + "\n",
 		},
 		"onlyOneSlice": {
 			Input: toIntf([]struct {
@@ -52,18 +77,59 @@ func TestMerge(t *testing.T) {
 				A []string
 				B int
 			}{{A: []string{"1"}, B: 33}, {A: []string{"2"}}, {A: []string{"3"}}}),
-			Want: `{"B":33,"A":["1","2","3"]}` + "\n",
+			Want: `{"A":["1","2","3"],"B":33}` + "\n",
+		},
+		"recvError": {
+			Input: toIntf([]struct {
+				A []string
+				B []int
+			}{{A: []string{"1"}, B: []int{33}}, {A: []string{"2"}}}),
+			Err:  status.Error(codes.Unavailable, "failed"),
+			Want: `{"A":["1","2"],"B":[33]}` + "\n",
+		},
+
+		"nested": {
+			Input: []any{
+				nested{Name: "a", Result: result{Rows: []int{1, 2}, Sums: map[string]int{"x": 1}}},
+				nested{Result: result{Rows: []int{3}, Total: 3, Sums: map[string]int{"y": 2}}},
+				nested{Name: "b", Result: result{Total: 6}},
+			},
+			Want: `{"name":"a","result":{"rows":[1,2,3],"total":3,"sums":{"x":1,"y":2}}}` + "\n",
+		},
+		"lastWins": {
+			Input: []any{
+				nested{Name: "a", Result: result{Rows: []int{1, 2}, Sums: map[string]int{"x": 1}}},
+				nested{Result: result{Rows: []int{3}, Total: 3, Sums: map[string]int{"x": 2}}},
+				nested{Name: "b", Result: result{Total: 6}},
+			},
+			Policy: MergeLastWins,
+			Want:   `{"name":"b","result":{"rows":[1,2,3],"total":6,"sums":{"x":2}}}` + "\n",
+		},
+		"wrongType": {
+			Input: []any{
+				map[string]any{"a": []int{1}, "b": 1},
+				map[string]any{"a": []int{2}, "b": []int{2}},
+				map[string]any{"a": []int{3}},
+			},
+			Want: `{"a":[1],"b":1}` + "\n" + `{"a":[2],"b":[2]}` + "\n" + `{"a":[3]}` + "\n",
 		},
 
 		"big1": {
 			Input: jsToIntf(strings.NewReader(jsBig1In)),
 			Want:  jsBig1Out,
+			// the parts are maps
+			AnyOrder: true,
 		},
 	} {
 		buf.Reset()
-		recv := &receiver{parts: tC.Input}
+		recv := &receiver{parts: tC.Input, err: tC.Err}
 		first, _ := recv.Recv()
-		mergeStreams(buf, first, recv, zlog.NewT(t).SLog())
+		if err := mergeStreams(buf, mergeConfig{Policy: tC.Policy}, first, recv, zlog.NewT(t).SLog()); !errors.Is(err, tC.Err) {
+			t.Errorf("%s: got error %+v, wanted %+v", tN, err, tC.Err)
+		}
+		if tC.Want == "" || buf.String() == tC.Want {
+			continue
+		}
 		_ = repComma
 		d, err := jsondiff.DiffStrings(
 			//repComma.Replace(tC.Want),
@@ -76,16 +142,114 @@ func TestMerge(t *testing.T) {
 		}
 		if d != "" {
 			t.Error(tN+":", d)
+		} else if !tC.AnyOrder {
+			t.Errorf("%s: got\n%s\nwanted (in this order)\n%s", tN, buf.String(), tC.Want)
 		}
 	}
 }
 
+func TestMergeOrder(t *testing.T) {
+	type Embedded struct {
+		E string `json:"e,omitempty"`
+	}
+	type inner struct {
+		Z int            `json:"z,omitempty"`
+		Y map[string]int `json:"y,omitempty"`
+		X []int          `json:"x"`
+	}
+	type part struct {
+		C string `json:"c,omitempty"`
+		Embedded
+		B      []string `json:"b"`
+		Hidden string   `json:"-"`
+		Dash   string   `json:"-,"`
+		A      inner    `json:"a"`
+	}
+	parts := []any{
+		part{B: []string{"1"}, A: inner{X: []int{1}, Y: map[string]int{"q": 1}}, Hidden: "h", Dash: "d"},
+		part{B: []string{"2"}, A: inner{Z: 2, Y: map[string]int{"p": 2}}, C: "c"},
+		part{Embedded: Embedded{E: "e"}},
+	}
+	want := `{"c":"c","e":"e","b":["1","2"],"-":"d","a":{"z":2,"y":{"p":2,"q":1},"x":[1]}}` + "\n"
+	for range 3 {
+		var buf bytes.Buffer
+		recv := &receiver{parts: parts}
+		first, _ := recv.Recv()
+		if err := mergeStreams(&buf, mergeConfig{}, first, recv, zlog.NewT(t).SLog()); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("got\n%s\nwanted\n%s", got, want)
+		}
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	type E struct{ A, C int }
+	type T struct {
+		B int `json:"A"`
+	}
+	type U struct{ C int }
+	type P struct {
+		E
+		A []int
+		B []int
+	}
+	type Tagged struct {
+		E
+		T
+	}
+	type Conflict struct {
+		E
+		U
+	}
+	type Twice struct {
+		E
+		P
+	}
+	type Pointer struct {
+		*E
+		D int `json:"d,omitempty"`
+	}
+	for _, v := range []any{
+		P{E: E{A: 7, C: 1}, A: []int{1}, B: []int{1}},
+		Tagged{E: E{A: 1, C: 2}, T: T{B: 3}},
+		Conflict{E: E{A: 1, C: 2}, U: U{C: 3}},
+		Twice{E: E{A: 1, C: 2}, P: P{E: E{A: 3, C: 4}, B: []int{5}}},
+		Pointer{E: &E{A: 1, C: 2}, D: 3},
+	} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.Token()
+		for dec.More() {
+			tok, _ := dec.Token()
+			want = append(want, tok.(string))
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var got []string
+		for _, f := range jsonFields(reflect.TypeOf(v)) {
+			got = append(got, f.name)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%T: got %q, wanted %q (%s)", v, got, want, b)
+		}
+	}
+}
+
+type retType struct {
+	Ret []map[string]any `json:"ret"`
+}
+
 func jsToIntf(r io.Reader) []any {
 	dec := json.NewDecoder(r)
 	res := make([]any, 0, 8)
-	type retType struct {
-		Ret []map[string]any `json:"ret"`
-	}
 	for {
 		var rt retType
 		if err := dec.Decode(&rt); err != nil {
@@ -109,11 +273,15 @@ func toIntf(someSlice any) []any {
 }
 
 type receiver struct {
+	err   error
 	parts []any
 }
 
 func (r *receiver) Recv() (any, error) {
 	if len(r.parts) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
 		return nil, io.EOF
 	}
 	p := r.parts[0]
@@ -641,3 +809,207 @@ const jsBig1Out = `
 {"row_num":245,"contract_number":10883864,"member_code":692188,"modkod":"22101","modrnev":"OTTHON","bid_id":60288132,"contract_status":"26","contract_status_name":"DÍJ SZEMPONTJÁBÓL ÁTDOLGOZOTT SZERZŐDÉS","contract_status_short":"ÉLŐ","contract_recording_date":"2012-02-20 00:00:00 +0200","contract_btkezd":"2012-01-28 00:00:00 +0200","contract_begin_date":"2012-01-27 00:00:00 +0200","contract_balance_date":"2017-12-31 00:00:00 +0200","contract_future_balance_date":"2017-12-31 00:00:00 +0200","contract_yearly_price":12775,"contract_anniversary":"12-31","client_name":"Tt Sped Kft.","client_code":2335604,"dealer_code":"0001001103","dealer_name":"Topa Mária Ilona","kockhely_irszam":"41770","kockhely_telepules":"FÖLDES","kockhely_cim":"Kállai utca 43. ","client_ppid":"41760","client_city":"SÁP"},
 {"row_num":246,"contract_number":10733025,"member_code":692188,"modkod":"22101","modrnev":"OTTHON","bid_id":60503164,"contract_status":"63","contract_status_name":"DÍJNEMFIZETÉS MIATT TÖRÖLT SZERZŐDÉS","contract_status_short":"TÖRÖLT","contract_recording_date":"2011-09-23 00:00:00 +0200","contract_btkezd":"2010-12-14 00:00:00 +0200","contract_begin_date":"2010-12-13 00:00:00 +0200","contract_deletion_valid_from":"2011-12-06 00:00:00 +0200","contract_balance_date":"2011-09-30 00:00:00 +0200","contract_future_balance_date":"2011-09-30 00:00:00 +0200","contract_yearly_price":20805,"contract_anniversary":"12-31","elvi_dijhatralek":3819,"client_name":"Tt Sped Kft.","client_code":1277407,"dealer_code":"0001001103","dealer_name":"Topa Mária Ilona","kockhely_irszam":"41760","kockhely_telepules":"SÁP","kockhely_cim":"Hrsz  _ ","client_ppid":"41760","client_city":"SÁP"},
 {"row_num":247,"contract_number":10610558,"member_code":692188,"modkod":"22101","modrnev":"OTTHON","bid_id":60469892,"contract_status":"26","contract_status_name":"DÍJ SZEMPONTJÁBÓL ÁTDOLGOZOTT SZERZŐDÉS","contract_status_short":"ÉLŐ","contract_recording_date":"2010-12-28 00:00:00 +0200","contract_btkezd":"2010-12-14 00:00:00 +0200","contract_begin_date":"2010-12-13 00:00:00 +0200","contract_balance_date":"2017-12-31 00:00:00 +0200","contract_future_balance_date":"2017-12-31 00:00:00 +0200","contract_yearly_price":28470,"contract_anniversary":"12-31","client_name":"Tt Sped Kft.","client_code":1277407,"dealer_code":"0001001103","dealer_name":"Topa Mária Ilona","kockhely_irszam":"41760","kockhely_telepules":"SÁP","kockhely_cim":"Hrsz  _ ","client_ppid":"41760","client_city":"SÁP"}]}`
+
+// jsonPart hides the type of the part, so it is merged by encoding and parsing it.
+type jsonPart struct{ v any }
+
+func (p jsonPart) MarshalJSON() ([]byte, error) { return json.Marshal(p.v) }
+
+func TestMergePlan(t *testing.T) {
+	type Inner struct {
+		When  time.Time         `json:"when"`
+		Tags  map[string]string `json:"tags,omitempty"`
+		Codes [2]int            `json:"codes"`
+	}
+	type part struct {
+		*Inner
+		Next  *Inner   `json:"next"`
+		Blob  []byte   `json:"blob"`
+		Rows  []Inner  `json:"rows"`
+		Zero  Inner    `json:"zero,omitzero"`
+		Count int      `json:"count,omitempty"`
+		Names []string `json:"names,omitempty"`
+	}
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	in := Inner{When: now, Tags: map[string]string{"a": "b"}, Codes: [2]int{1, 2}}
+	parts := []any{
+		part{Inner: &in, Next: &in, Blob: []byte("x"), Rows: []Inner{in}, Zero: in, Count: 1, Names: []string{"a"}},
+		part{Rows: []Inner{{When: now}}, Names: []string{"b"}},
+		part{Inner: &Inner{Tags: map[string]string{"c": "d"}}, Count: 3},
+	}
+	if p := mergePlanOf(reflect.TypeOf(parts[0])); p == nil || !p.rigid {
+		t.Fatalf("got plan %+v", p)
+	}
+	merge := func(parts []any) string {
+		var buf bytes.Buffer
+		recv := &receiver{parts: parts}
+		first, _ := recv.Recv()
+		if err := mergeStreams(&buf, mergeConfig{}, first, recv, zlog.NewT(t).SLog()); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	got := merge(parts)
+	hidden := make([]any, len(parts))
+	for i, p := range parts {
+		hidden[i] = jsonPart{p}
+	}
+	if want := merge(hidden); got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+
+	// the shallower field dominates the promoted one, as by encoding/json
+	type E struct{ A int }
+	type P struct {
+		E
+		A []int
+		B []int
+	}
+	if p := mergePlanOf(reflect.TypeOf(P{})); p == nil || len(p.fields) != 2 {
+		t.Fatalf("got plan %+v", p)
+	}
+	if got, want := merge([]any{P{E: E{A: 7}, A: []int{1}, B: []int{1}}, P{E: E{A: 8}, B: []int{2}}}), `{"A":[1],"B":[1,2]}`+"\n"; got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+}
+
+func TestMergeMessagePlan(t *testing.T) {
+	for _, tc := range []struct {
+		Type  any
+		Rigid bool
+	}{
+		{&descriptorpb.FileDescriptorProto{}, true},
+		{&errdetails.RetryInfo{}, true},
+		{&typepb.Option{}, false},
+		{&structpb.Struct{}, false},
+	} {
+		p := mergePlanOf(reflect.TypeOf(tc.Type))
+		if got := p != nil && p.rigid; got != tc.Rigid {
+			t.Errorf("%T: got rigid=%t, wanted %t", tc.Type, got, tc.Rigid)
+		}
+	}
+
+	opt := func(o *descriptorpb.UninterpretedOption) *descriptorpb.FileOptions {
+		return &descriptorpb.FileOptions{UninterpretedOption: []*descriptorpb.UninterpretedOption{o}}
+	}
+	for name, parts := range map[string][]any{
+		"file": {
+			&descriptorpb.FileDescriptorProto{
+				Name: proto.String("a.proto"), Dependency: []string{"x"},
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("M"), Field: []*descriptorpb.FieldDescriptorProto{{
+					Name: proto.String("f"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+				}}}},
+				Options: opt(&descriptorpb.UninterpretedOption{
+					PositiveIntValue: proto.Uint64(1 << 60), NegativeIntValue: proto.Int64(-3),
+					DoubleValue: proto.Float64(1e-9), StringValue: []byte("\x00\xff"),
+					IdentifierValue: proto.String("tab\there \"q\" \x01 árvíztűrő"),
+				}),
+			},
+			&descriptorpb.FileDescriptorProto{
+				Name: proto.String("b.proto"), Dependency: []string{"y", "z"},
+				Options: opt(&descriptorpb.UninterpretedOption{DoubleValue: proto.Float64(math.Inf(-1))}),
+			},
+			&descriptorpb.FileDescriptorProto{Syntax: proto.String("proto3"), Options: &descriptorpb.FileOptions{
+				JavaPackage: proto.String("j"), OptimizeFor: descriptorpb.FileOptions_CODE_SIZE.Enum(),
+			}},
+		},
+		"map": {
+			&errdetails.ErrorInfo{Reason: "a", Metadata: map[string]string{"x": "1"}},
+			&errdetails.ErrorInfo{Domain: "d", Metadata: map[string]string{"y": "2"}},
+		},
+		"wellKnown": {
+			&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Second)},
+			&errdetails.RetryInfo{RetryDelay: durationpb.New(2 * time.Second)},
+		},
+	} {
+		for _, cfg := range []mergeConfig{{}, {Policy: MergeLastWins}, {Codec: JSONCodec{UseJSONNames: true}}} {
+			hidden := make([]any, len(parts))
+			for i, p := range parts {
+				hidden[i] = jsonPart{cfg.Codec.value(p)}
+			}
+			merge := func(parts []any) string {
+				var buf bytes.Buffer
+				recv := &receiver{parts: parts}
+				first, _ := recv.Recv()
+				if err := mergeStreams(&buf, cfg, first, recv, zlog.NewT(t).SLog()); err != nil {
+					t.Fatal(err)
+				}
+				// protojson may add spaces
+				var lines []string
+				dec := json.NewDecoder(&buf)
+				for dec.More() {
+					var raw json.RawMessage
+					if err := dec.Decode(&raw); err != nil {
+						t.Fatal(err)
+					}
+					var b bytes.Buffer
+					if err := json.Compact(&b, raw); err != nil {
+						t.Fatal(err)
+					}
+					lines = append(lines, b.String())
+				}
+				return strings.Join(lines, "\n")
+			}
+			if got, want := merge(parts), merge(hidden); got != want {
+				t.Errorf("%s %+v: got\n%s\nwanted\n%s", name, cfg, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkMerge(b *testing.B) {
+	parts := jsToIntf(strings.NewReader(jsBig1In))
+	// the same rows, 4 per part
+	var small []any
+	for _, p := range parts {
+		rows := p.(retType).Ret
+		for len(rows) != 0 {
+			n := min(4, len(rows))
+			small = append(small, retType{Ret: rows[:n]})
+			rows = rows[n:]
+		}
+	}
+	hide := func(parts []any) []any {
+		hidden := make([]any, len(parts))
+		for i, p := range parts {
+			hidden[i] = jsonPart{p}
+		}
+		return hidden
+	}
+	// the same rows as proto messages, 4 per part
+	var messages []any
+	for _, p := range small {
+		var bad errdetails.BadRequest
+		for _, row := range p.(retType).Ret {
+			bad.FieldViolations = append(bad.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field: fmt.Sprint(row["row_num"]), Description: fmt.Sprint(row["client_name"]),
+				Reason: fmt.Sprint(row["contract_status_name"]),
+			})
+		}
+		messages = append(messages, &bad)
+	}
+	hideMessages := make([]any, len(messages))
+	for i, p := range messages {
+		hideMessages[i] = jsonPart{JSONCodec{}.value(p)}
+	}
+	logger := zlog.NewT(b).SLog()
+	for _, bc := range []struct {
+		Name  string
+		Parts []any
+	}{
+		{"big1/plan", parts}, {"big1/json", hide(parts)},
+		{"small/plan", small}, {"small/json", hide(small)},
+		{"proto/plan", messages}, {"proto/json", hideMessages},
+	} {
+		b.Run(bc.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				recv := &receiver{parts: bc.Parts}
+				first, _ := recv.Recv()
+				if err := mergeStreams(io.Discard, mergeConfig{}, first, recv, logger); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MergeParts receives all the parts, and merges them into one T, as the merging JSONHandler does:
+// the slices (repeated fields) are concatenated, the structs (messages) and maps are merged,
+// and the first non-null value of the other fields is kept.
+//
+// Returns io.EOF if there are no parts.
+func MergeParts[T any](recv Receiver) (T, error) {
+	var zero T
+	m := merger{}
+	defer m.Close()
+	var root mergeNode
+	var n int
+	for part, err := range All(recv) {
+		if err != nil {
+			return zero, err
+		}
+		if err = m.mergePart(&root, part); err != nil {
+			return zero, fmt.Errorf("merge part %d: %w", n, err)
+		}
+		n++
+	}
+	if n == 0 {
+		return zero, io.EOF
+	}
+	var buf bytes.Buffer
+	if err := m.write(&buf, &root, nil); err != nil {
+		return zero, err
+	}
+	res := zero
+	ptr := any(&res)
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		// unmarshal into a new T, to use protojson for the messages
+		res = reflect.New(t.Elem()).Interface().(T)
+		ptr = res
+	}
+	if err := m.Codec.Unmarshal(buf.Bytes(), ptr); err != nil {
+		return zero, err
+	}
+	return res, nil
+}
+
+// mergePart merges the part into the root, following its type's rigid mergePlan,
+// or encoding and parsing it.
+func (m *merger) mergePart(root *mergeNode, part any) error {
+	if plan := m.planOf(reflect.TypeOf(part)); plan != nil {
+		return m.mergeValue(root, "", reflect.ValueOf(part), plan)
+	}
+	b, err := m.Codec.Marshal(part)
+	if err != nil {
+		return err
+	}
+	return m.merge(root, "", b)
+}
+
+// MergeMessages receives all the parts, and merges them into one message with protoreflect,
+// with the semantics of MergeParts: the repeated fields are appended, the maps and messages are merged,
+// and the first populated value of the scalars (and oneofs) is kept.
+//
+// Returns io.EOF if there are no parts.
+func MergeMessages[M proto.Message](recv Receiver) (M, error) {
+	var res M
+	var n int
+	for part, err := range All(recv) {
+		if err != nil {
+			return res, err
+		}
+		m, ok := part.(M)
+		if !ok {
+			return res, fmt.Errorf("part %d is %T, not %T: %w", n, part, res, ErrWrongType)
+		}
+		if n == 0 {
+			res = proto.Clone(m).(M)
+		} else {
+			mergeMessage(res.ProtoReflect(), m.ProtoReflect())
+		}
+		n++
+	}
+	if n == 0 {
+		return res, io.EOF
+	}
+	return res, nil
+}
+
+// mergeMessage merges src into dst.
+func mergeMessage(dst, src protoreflect.Message) {
+	src.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			if set := dst.WhichOneof(od); set != nil && set.Number() != fd.Number() {
+				return true
+			}
+		}
+		switch {
+		case fd.IsList():
+			dl, sl := dst.Mutable(fd).List(), v.List()
+			for i := range sl.Len() {
+				dl.Append(cloneValue(sl.Get(i)))
+			}
+		case fd.IsMap():
+			dm := dst.Mutable(fd).Map()
+			isMessage := fd.MapValue().Message() != nil
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				if !dm.Has(k) {
+					dm.Set(k, cloneValue(mv))
+				} else if isMessage {
+					mergeMessage(dm.Mutable(k).Message(), mv.Message())
+				}
+				return true
+			})
+		case fd.Message() != nil:
+			if dst.Has(fd) {
+				mergeMessage(dst.Mutable(fd).Message(), v.Message())
+			} else {
+				dst.Set(fd, cloneValue(v))
+			}
+		default:
+			if !dst.Has(fd) {
+				dst.Set(fd, cloneValue(v))
+			}
+		}
+		return true
+	})
+}
+
+// cloneValue returns a deep copy of the messages and bytes, and the other values as is.
+func cloneValue(v protoreflect.Value) protoreflect.Value {
+	switch x := v.Interface().(type) {
+	case protoreflect.Message:
+		return protoreflect.ValueOfMessage(proto.Clone(x.Interface()).ProtoReflect())
+	case []byte:
+		return protoreflect.ValueOfBytes(bytes.Clone(x))
+	}
+	return v
+}
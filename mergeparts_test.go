@@ -0,0 +1,105 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMergeParts(t *testing.T) {
+	type result struct {
+		Sums  map[string]int `json:"sums,omitempty"`
+		Rows  []int          `json:"rows"`
+		Total int            `json:"total,omitempty"`
+	}
+	type part struct {
+		Name   string `json:"name,omitempty"`
+		Result result `json:"result"`
+	}
+	got, err := MergeParts[part](&receiver{parts: []any{
+		part{Name: "a", Result: result{Rows: []int{1, 2}, Sums: map[string]int{"x": 1}}},
+		part{Result: result{Rows: []int{3}, Total: 3, Sums: map[string]int{"y": 2}}},
+		part{Name: "b", Result: result{Total: 6}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := part{Name: "a", Result: result{Rows: []int{1, 2, 3}, Total: 3, Sums: map[string]int{"x": 1, "y": 2}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	if _, err = MergeParts[part](&receiver{}); !errors.Is(err, io.EOF) {
+		t.Errorf("no parts: got %+v, wanted EOF", err)
+	}
+	failed := status.Error(codes.Unavailable, "failed")
+	if _, err = MergeParts[part](&receiver{parts: []any{want}, err: failed}); !errors.Is(err, failed) {
+		t.Errorf("got %+v, wanted %+v", err, failed)
+	}
+}
+
+func TestMergeMessages(t *testing.T) {
+	for tN, tC := range map[string]struct {
+		Want  proto.Message
+		Parts []any
+	}{
+		"list": {
+			Parts: []any{
+				&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "a"}}},
+				&errdetails.BadRequest{},
+				&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "b"}, {Field: "c"}}},
+			},
+			Want: &errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "a"}, {Field: "b"}, {Field: "c"}}},
+		},
+		"map": {
+			Parts: []any{
+				&errdetails.ErrorInfo{Reason: "a", Metadata: map[string]string{"x": "1"}},
+				&errdetails.ErrorInfo{Reason: "b", Domain: "d", Metadata: map[string]string{"x": "2", "y": "2"}},
+			},
+			Want: &errdetails.ErrorInfo{Reason: "a", Domain: "d", Metadata: map[string]string{"x": "1", "y": "2"}},
+		},
+	} {
+		var got, viaJSON proto.Message
+		var err error
+		switch tC.Want.(type) {
+		case *errdetails.BadRequest:
+			if got, err = MergeMessages[*errdetails.BadRequest](&receiver{parts: tC.Parts}); err == nil {
+				viaJSON, err = MergeParts[*errdetails.BadRequest](&receiver{parts: tC.Parts})
+			}
+		case *errdetails.ErrorInfo:
+			if got, err = MergeMessages[*errdetails.ErrorInfo](&receiver{parts: tC.Parts}); err == nil {
+				viaJSON, err = MergeParts[*errdetails.ErrorInfo](&receiver{parts: tC.Parts})
+			}
+		}
+		if err != nil {
+			t.Fatalf("%s: %+v", tN, err)
+		}
+		if !proto.Equal(got, tC.Want) {
+			t.Errorf("%s: got %v, wanted %v", tN, got, tC.Want)
+		}
+		if !proto.Equal(viaJSON, tC.Want) {
+			t.Errorf("%s: MergeParts got %v, wanted %v", tN, viaJSON, tC.Want)
+		}
+	}
+
+	// the parts are not modified
+	first := &errdetails.ErrorInfo{Reason: "a"}
+	if _, err := MergeMessages[*errdetails.ErrorInfo](&receiver{parts: []any{first, &errdetails.ErrorInfo{Domain: "d"}}}); err != nil {
+		t.Fatal(err)
+	} else if first.Domain != "" {
+		t.Errorf("first part modified: %v", first)
+	}
+	if _, err := MergeMessages[*errdetails.ErrorInfo](&receiver{parts: []any{first, &errdetails.BadRequest{}}}); !errors.Is(err, ErrWrongType) {
+		t.Errorf("got %+v, wanted %+v", err, ErrWrongType)
+	}
+}
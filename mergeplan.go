@@ -0,0 +1,297 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// mergePlan is the cached plan of merging the values of a struct type or a proto message,
+// without encoding and parsing them as a whole.
+type mergePlan struct {
+	// message is the descriptor of the proto message, merged with protoreflect (see mergeMessage).
+	message protoreflect.MessageDescriptor
+	fields  []planField
+	// rigid is true if the JSON kind of the fields cannot change from value to value,
+	// so merging the values of the type cannot fail.
+	rigid bool
+}
+
+// planField is a field of a mergePlan.
+type planField struct {
+	plan  *mergePlan // of nested structs
+	name  string
+	index []int
+	// kind is '[' for slices and arrays, '{' for nested structs, 0 for the rest (encoded with encoding/json).
+	kind                byte
+	omitEmpty, omitZero bool
+	// addr is true if the field's type implements json.Marshaler or encoding.TextMarshaler with a pointer receiver.
+	addr bool
+}
+
+var mergePlans sync.Map // reflect.Type -> *mergePlan
+
+// mergePlanOf returns the mergePlan of the type, or nil if it is not a plain struct
+// (or a pointer to it) encoded by encoding/json, nor a proto message (other than a well-known type).
+func mergePlanOf(t reflect.Type) *mergePlan {
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Pointer && t.Implements(protoMessageType) {
+		if p, ok := mergePlans.Load(t); ok {
+			return p.(*mergePlan)
+		}
+		p := messagePlanOf(reflect.Zero(t).Interface().(proto.Message).ProtoReflect().Descriptor())
+		if p != nil {
+			mergePlans.Store(t, p)
+		}
+		return p
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if p, ok := mergePlans.Load(t); ok {
+		return p.(*mergePlan)
+	}
+	p := buildMergePlan(t, make(map[reflect.Type]*mergePlan))
+	if p != nil {
+		mergePlans.Store(t, p)
+	}
+	return p
+}
+
+// planOf returns the rigid mergePlan of the type, or nil if its values have to be encoded and parsed.
+func (cfg mergeConfig) planOf(t reflect.Type) *mergePlan {
+	p := mergePlanOf(t)
+	if p == nil || !p.rigid || p.message != nil && cfg.Codec.EmitUnpopulated {
+		return nil
+	}
+	return p
+}
+
+// isPlain reports whether the type is a struct encoded field by field by encoding/json.
+func isPlain(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct &&
+		!t.Implements(jsonMarshalerType) && !t.Implements(textMarshalerType) &&
+		!reflect.PointerTo(t).Implements(jsonMarshalerType) && !reflect.PointerTo(t).Implements(textMarshalerType) &&
+		!reflect.PointerTo(t).Implements(protoMessageType)
+}
+
+func buildMergePlan(t reflect.Type, building map[reflect.Type]*mergePlan) *mergePlan {
+	if !isPlain(t) {
+		return nil
+	}
+	if p := building[t]; p != nil {
+		return p
+	}
+	p := &mergePlan{rigid: true}
+	building[t] = p
+	for _, f := range jsonFields(t) {
+		// the fields are promoted from plain embedded structs only
+		et := t
+		for _, i := range f.Index[:len(f.Index)-1] {
+			for et = et.Field(i).Type; et.Kind() == reflect.Pointer; et = et.Elem() {
+			}
+			if !isPlain(et) {
+				delete(building, t)
+				return nil
+			}
+		}
+		pf := planField{name: f.name, index: f.Index}
+		for opts := f.opts; opts != ""; {
+			var opt string
+			opt, opts, _ = strings.Cut(opts, ",")
+			switch opt {
+			case "omitempty":
+				pf.omitEmpty = true
+			case "omitzero":
+				pf.omitZero = true
+			case "string":
+				delete(building, t)
+				return nil
+			}
+		}
+		ft := f.Type
+		for ; ft.Kind() == reflect.Pointer; ft = ft.Elem() {
+		}
+		switch {
+		case ft.Kind() != reflect.Pointer && !ft.Implements(jsonMarshalerType) && !ft.Implements(textMarshalerType) &&
+			(reflect.PointerTo(ft).Implements(jsonMarshalerType) || reflect.PointerTo(ft).Implements(textMarshalerType)):
+			pf.addr = true
+		case (ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 || ft.Kind() == reflect.Array) &&
+			!ft.Implements(jsonMarshalerType) && !ft.Implements(textMarshalerType):
+			pf.kind = '['
+		case isPlain(ft):
+			if pf.plan = buildMergePlan(ft, building); pf.plan != nil {
+				pf.kind = '{'
+			}
+		}
+		p.rigid = p.rigid && pf.rigid(f.Type)
+		p.fields = append(p.fields, pf)
+	}
+	return p
+}
+
+// rigid reports whether the JSON kind of the field's values is always the same (or null).
+func (f planField) rigid(t reflect.Type) bool {
+	switch f.kind {
+	case '[':
+		return true
+	case '{':
+		return f.plan.rigid
+	}
+	return rigidType(t)
+}
+
+func rigidType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return true
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return false
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Slice, reflect.Array:
+		return true
+	case reflect.Map:
+		return rigidType(t.Elem())
+	}
+	return false
+}
+
+// mergeValue merges the struct value (or proto message) into the node, following the plan.
+func (m *merger) mergeValue(nd *mergeNode, name string, rv reflect.Value, p *mergePlan) error {
+	if p.message != nil {
+		return m.mergeMessage(nd, name, rv.Interface().(proto.Message).ProtoReflect())
+	}
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if err := nd.setKind(name, '{'); err != nil {
+		return err
+	}
+	for _, f := range p.fields {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok || f.omitEmpty && isEmptyValue(fv) || f.omitZero && isZeroValue(fv) {
+			continue
+		}
+		child := nd.child(f.name)
+		nm := f.name
+		if name != "" {
+			nm = name + "." + f.name
+		}
+		var err error
+		switch f.kind {
+		case '[':
+			err = m.appendElems(child, nm, fv)
+		case '{':
+			err = m.mergeValue(child, nm, fv, f.plan)
+		default:
+			v := fv.Interface()
+			if f.addr && fv.CanAddr() {
+				v = fv.Addr().Interface()
+			}
+			var b []byte
+			if b, err = json.Marshal(v); err == nil {
+				err = m.merge(child, nm, b)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendElems encodes the elements of the slice or array to the node's buffer, without the brackets.
+func (m *merger) appendElems(nd *mergeNode, name string, sv reflect.Value) error {
+	for sv.Kind() == reflect.Pointer {
+		if sv.IsNil() {
+			return nil
+		}
+		sv = sv.Elem()
+	}
+	if sv.Kind() == reflect.Slice && sv.IsNil() {
+		return nil
+	}
+	if sv.Len() == 0 {
+		return m.appendArray(nd, name, nil)
+	}
+	v := sv.Interface()
+	if sv.CanAddr() {
+		v = sv.Addr().Interface()
+	}
+	// encode into the reused buffer, as json.Marshal would
+	m.buf.Reset()
+	if m.enc == nil {
+		m.enc = json.NewEncoder(&m.buf)
+	}
+	if err := m.enc.Encode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	b := bytes.TrimSpace(m.buf.Bytes())
+	return m.appendArray(nd, name, b[1:len(b)-1])
+}
+
+// fieldByIndex returns the (embedded) field, or false if it is in a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether the value is empty for the omitempty option of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// isZeroValue reports whether the value is zero for the omitzero option of encoding/json.
+func isZeroValue(v reflect.Value) bool {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return true
+		}
+		return z.IsZero()
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,176 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// messagePlanOf returns the mergePlan of the message, or nil for the well-known types,
+// which are encoded by protojson specially.
+func messagePlanOf(md protoreflect.MessageDescriptor) *mergePlan {
+	if wellKnownSchema(string(md.FullName())) != nil {
+		return nil
+	}
+	return &mergePlan{message: md, rigid: rigidMessage(md, make(map[protoreflect.FullName]bool))}
+}
+
+// rigidMessage reports whether the JSON kind of the message's fields cannot change from message to message.
+//
+// The Value, Struct and Any well-known types (as singular fields or map values) can hold anything.
+func rigidMessage(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) bool {
+	if seen[md.FullName()] {
+		return true
+	}
+	seen[md.FullName()] = true
+	fds := md.Fields()
+	for i := range fds.Len() {
+		fd := fds.Get(i)
+		if fd.IsList() {
+			continue
+		}
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		vd := fd.Message()
+		if vd == nil {
+			continue
+		}
+		switch vd.FullName() {
+		case "google.protobuf.Value", "google.protobuf.Struct", "google.protobuf.Any":
+			return false
+		}
+		if wellKnownSchema(string(vd.FullName())) == nil && !rigidMessage(vd, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeMessage merges the populated fields of the message into the node, as protojson encodes them:
+// the lists are appended to the arrays, the maps and messages are merged,
+// and only the scalars and well-known types are encoded.
+func (m *merger) mergeMessage(nd *mergeNode, name string, msg protoreflect.Message) error {
+	if !msg.IsValid() {
+		return nil
+	}
+	if err := nd.setKind(name, '{'); err != nil {
+		return err
+	}
+	var err error
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		k := m.Codec.fieldName(fd)
+		nm := k
+		if name != "" {
+			nm = name + "." + k
+		}
+		child := nd.child(k)
+		switch {
+		case fd.IsList():
+			err = m.appendList(child, nm, fd, v.List())
+		case fd.IsMap():
+			err = m.mergeMap(child, nm, fd.MapValue(), v.Map())
+		default:
+			err = m.mergeSingular(child, nm, fd, v)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// mergeSingular merges one value of the field into the node.
+func (m *merger) mergeSingular(nd *mergeNode, name string, fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	if md := fd.Message(); md != nil && wellKnownSchema(string(md.FullName())) == nil {
+		return m.mergeMessage(nd, name, v.Message())
+	}
+	if nd.kind == 's' && m.Policy == MergeFirstWins {
+		return nil
+	}
+	b, err := m.appendProtoValue(nil, fd, v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return m.merge(nd, name, b)
+}
+
+// mergeMap merges the entries of the map into the object node.
+func (m *merger) mergeMap(nd *mergeNode, name string, vd protoreflect.FieldDescriptor, mp protoreflect.Map) error {
+	if err := nd.setKind(name, '{'); err != nil {
+		return err
+	}
+	var err error
+	mp.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		ks := k.String()
+		err = m.mergeSingular(nd.child(ks), name+"."+ks, vd, v)
+		return err == nil
+	})
+	return err
+}
+
+// appendList encodes the elements of the list to the node's buffer.
+func (m *merger) appendList(nd *mergeNode, name string, fd protoreflect.FieldDescriptor, l protoreflect.List) error {
+	b := m.scratch[:0]
+	for i := range l.Len() {
+		if i != 0 {
+			b = append(b, ',')
+		}
+		var err error
+		if b, err = m.appendProtoValue(b, fd, l.Get(i)); err != nil {
+			return fmt.Errorf("%s[%d]: %w", name, i, err)
+		}
+	}
+	m.scratch = b[:0]
+	return m.appendArray(nd, name, b)
+}
+
+// appendProtoValue appends one value of the field, as protojson encodes it.
+func (m *merger) appendProtoValue(b []byte, fd protoreflect.FieldDescriptor, v protoreflect.Value) ([]byte, error) {
+	msg := protoScalar(fd, v)
+	if msg == nil {
+		return b, fmt.Errorf("%s has unknown kind %v", fd.FullName(), fd.Kind())
+	}
+	return m.Codec.marshalOptions().MarshalAppend(b, msg)
+}
+
+// protoScalar returns the message protojson encodes as the value of the field:
+// the message itself, or the well-known wrapper of the scalar.
+func protoScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) proto.Message {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return wrapperspb.Bool(v.Bool())
+	case protoreflect.StringKind:
+		return wrapperspb.String(v.String())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return wrapperspb.Int32(int32(v.Int()))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return wrapperspb.UInt32(uint32(v.Uint()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return wrapperspb.Int64(v.Int())
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return wrapperspb.UInt64(v.Uint())
+	case protoreflect.FloatKind:
+		return wrapperspb.Float(float32(v.Float()))
+	case protoreflect.DoubleKind:
+		return wrapperspb.Double(v.Float())
+	case protoreflect.BytesKind:
+		return wrapperspb.Bytes(v.Bytes())
+	case protoreflect.EnumKind:
+		if fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return structpb.NewNullValue()
+		}
+		if ed := fd.Enum().Values().ByNumber(v.Enum()); ed != nil {
+			return wrapperspb.String(string(ed.Name()))
+		}
+		return wrapperspb.Int32(int32(v.Enum()))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return v.Message().Interface()
+	}
+	return nil
+}
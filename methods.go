@@ -0,0 +1,230 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MethodsPath is the name JSONHandler serves the index of the methods under, for GET requests.
+const MethodsPath = "_methods"
+
+// Streaming kinds of the methods.
+const (
+	StreamingUnary  = "unary"
+	StreamingServer = "server"
+	StreamingClient = "client"
+	StreamingBidi   = "bidi"
+)
+
+// MethodIndexEntry is an element of the index of the methods served by JSONHandler.
+type MethodIndexEntry struct {
+	Input  map[string]any `json:"input,omitempty"`
+	Output map[string]any `json:"output,omitempty"`
+	Name   string         `json:"name"`
+	// FullMethod is the full gRPC method name, empty if unknown.
+	FullMethod  string `json:"fullMethod,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Streaming is the streaming kind of the method (unary, server, client, bidi), empty if unknown.
+	Streaming string   `json:"streaming,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// streamingKind returns the streaming kind of the method.
+func streamingKind(cs, ss bool) string {
+	switch {
+	case cs && ss:
+		return StreamingBidi
+	case cs:
+		return StreamingClient
+	case ss:
+		return StreamingServer
+	}
+	return StreamingUnary
+}
+
+// methodDesc is the description of a method.
+type methodDesc struct {
+	input, output                   protoreflect.MessageDescriptor
+	fullMethod, streaming, comments string
+	tags                            []string
+}
+
+// describeMethod describes the named method from the MethodInfo of the Client,
+// or the descriptor of the method with the input's message type from the global registry.
+func describeMethod(c Client, name string, input any) methodDesc {
+	var d methodDesc
+	if mi, ok := c.(MethodInfo); ok {
+		d.input, d.output = mi.Descriptors(name)
+		d.fullMethod, d.comments, d.tags = mi.FullMethod(name), mi.Comments(name), mi.Tags(name)
+		d.streaming = streamingKind(mi.Streaming(name))
+	} else {
+		if tagger, ok := c.(interface{ Tags(string) []string }); ok {
+			d.tags = tagger.Tags(name)
+		}
+		if md := findMethod(name, input); md != nil {
+			d.input, d.output = md.Input(), md.Output()
+			d.fullMethod = "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+			d.comments = md.ParentFile().SourceLocations().ByDescriptor(md).LeadingComments
+			d.streaming = streamingKind(md.IsStreamingClient(), md.IsStreamingServer())
+		}
+	}
+	d.comments = strings.TrimSpace(d.comments)
+	return d
+}
+
+// MethodIndex returns the index of the methods of the Client, with their tags, streaming kind,
+// and the JSON Schema of their input and output.
+func (h JSONHandler) MethodIndex() []MethodIndexEntry {
+	names := h.List()
+	slices.Sort(names)
+	index := make([]MethodIndexEntry, 0, len(names))
+	for _, name := range names {
+		inp := h.Input(name)
+		if inp == nil {
+			continue
+		}
+		d := describeMethod(h.Client, name, inp)
+		e := MethodIndexEntry{
+			Name: name, FullMethod: d.fullMethod, Description: d.comments,
+			Streaming: d.streaming, Tags: d.tags,
+		}
+		g := schemaGen{defs: make(map[string]any), refPrefix: "#/$defs/", codec: h.JSON}
+		e.Input = g.standalone(g.inputSchema(d, inp))
+		if d.output != nil {
+			g = schemaGen{defs: make(map[string]any), refPrefix: "#/$defs/", codec: h.JSON}
+			e.Output = g.standalone(g.messageSchema(d.output))
+		}
+		index = append(index, e)
+	}
+	return index
+}
+
+func (h JSONHandler) serveMethods(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.MethodIndex()); err != nil {
+		h.getLogger(r.Context()).Error("encode methods", "error", err)
+	}
+}
+
+// inputSchema returns the JSON Schema of the method's input: of its message descriptor, if known.
+func (g schemaGen) inputSchema(d methodDesc, inp any) map[string]any {
+	if d.input != nil {
+		return g.messageSchema(d.input)
+	}
+	return g.schema(reflect.TypeOf(inp))
+}
+
+// standalone returns the schema with the $schema and the $defs.
+func (g schemaGen) standalone(s map[string]any) map[string]any {
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	if len(g.defs) != 0 {
+		s["$defs"] = g.defs
+	}
+	return s
+}
+
+// messageSchema returns the JSON Schema of the message as protojson encodes it,
+// referenced from defs.
+func (g schemaGen) messageSchema(md protoreflect.MessageDescriptor) map[string]any {
+	name := string(md.FullName())
+	if s := wellKnownSchema(name); s != nil {
+		return s
+	}
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = nil // against recursion
+		fields := md.Fields()
+		props := make(map[string]any, fields.Len())
+		for i := range fields.Len() {
+			fd := fields.Get(i)
+			var s map[string]any
+			switch {
+			case fd.IsMap():
+				s = map[string]any{"type": "object", "additionalProperties": g.fieldSchema(fd.MapValue())}
+			case fd.IsList():
+				s = map[string]any{"type": "array", "items": g.fieldSchema(fd)}
+			default:
+				s = g.fieldSchema(fd)
+			}
+			props[g.codec.fieldName(fd)] = s
+		}
+		s := map[string]any{"type": "object", "properties": props}
+		if c := md.ParentFile().SourceLocations().ByDescriptor(md).LeadingComments; c != "" {
+			s["description"] = c
+		}
+		g.defs[name] = s
+	}
+	return map[string]any{"$ref": g.refPrefix + name}
+}
+
+// wellKnownSchema returns the JSON Schema of the well-known type as protojson encodes it,
+// or nil if it is not a well-known type.
+func wellKnownSchema(fullName string) map[string]any {
+	switch fullName {
+	case "google.protobuf.Timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration":
+		return map[string]any{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?s$`}
+	case "google.protobuf.FieldMask":
+		return map[string]any{"type": "string"}
+	case "google.protobuf.Empty", "google.protobuf.Struct":
+		return map[string]any{"type": "object"}
+	case "google.protobuf.ListValue":
+		return map[string]any{"type": "array"}
+	case "google.protobuf.Value":
+		return map[string]any{}
+	case "google.protobuf.Any":
+		return map[string]any{"type": "object", "properties": map[string]any{"@type": map[string]any{"type": "string"}}}
+	case "google.protobuf.BoolValue":
+		return map[string]any{"type": "boolean"}
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return map[string]any{"type": "integer", "format": "int32"}
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return map[string]any{"type": "string", "format": "int64"}
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return map[string]any{"type": "number"}
+	case "google.protobuf.StringValue":
+		return map[string]any{"type": "string"}
+	case "google.protobuf.BytesValue":
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	}
+	return nil
+}
+
+// fieldSchema returns the JSON Schema of one value of the field.
+func (g schemaGen) fieldSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		enum := make([]string, 0, values.Len())
+		for i := range values.Len() {
+			enum = append(enum, string(values.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": enum}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.messageSchema(fd.Message())
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestMethodIndex(t *testing.T) {
+	w := httptest.NewRecorder()
+	JSONHandler{Client: healthClient{}}.ServeHTTP(w, httptest.NewRequest("GET", "/api/"+MethodsPath, nil))
+	if w.Code != 200 {
+		t.Fatalf("got %d: %s", w.Code, w.Body.String())
+	}
+	var index []MethodIndexEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &index); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]MethodIndexEntry, len(index))
+	for _, e := range index {
+		got[e.Name] = e
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d methods, wanted 3", len(got))
+	}
+	for name, streaming := range map[string]string{"Check": StreamingUnary, "Watch": StreamingServer, "Other": ""} {
+		if got[name].Streaming != streaming {
+			t.Errorf("%s: got %q, wanted %q", name, got[name].Streaming, streaming)
+		}
+	}
+	if tags := got["Check"].Tags; len(tags) != 1 || tags[0] != "health" {
+		t.Errorf("Check tags: %q", tags)
+	}
+	defs, _ := got["Check"].Output["$defs"].(map[string]any)
+	resp, _ := defs["grpc.health.v1.HealthCheckResponse"].(map[string]any)
+	status, _ := resp["properties"].(map[string]any)["status"].(map[string]any)
+	if status["type"] != "string" || status["enum"] == nil {
+		t.Errorf("HealthCheckResponse.status: %v", status)
+	}
+	if got["Other"].Input["properties"] == nil || got["Other"].Output != nil {
+		t.Errorf("Other: %+v", got["Other"])
+	}
+	if fm := got["Watch"].FullMethod; fm != "/grpc.health.v1.Health/Watch" {
+		t.Errorf("Watch full method: %q", fm)
+	}
+
+	index = JSONHandler{Client: infoClient{}}.MethodIndex()
+	if len(index) != 3 {
+		t.Fatalf("got %d methods, wanted 3", len(index))
+	}
+	for _, e := range index {
+		if e.Name != "Other" {
+			continue
+		}
+		if e.Streaming != StreamingClient || e.FullMethod != "/test.Svc/Other" ||
+			e.Description != "Other is described." || len(e.Tags) != 1 || e.Tags[0] != "other" || e.Output == nil {
+			t.Errorf("Other: %+v", e)
+		}
+		// the input is described by the descriptor, not by the Go type
+		if defs, _ := e.Input["$defs"].(map[string]any); defs["grpc.health.v1.HealthCheckRequest"] == nil {
+			t.Errorf("Other input: %+v", e.Input)
+		}
+	}
+}
+
+// infoClient is a healthClient which describes its methods by MethodInfo.
+type infoClient struct{ healthClient }
+
+func (infoClient) Tags(name string) []string          { return []string{strings.ToLower(name)} }
+func (infoClient) FullMethod(name string) string      { return "/test.Svc/" + name }
+func (infoClient) Streaming(name string) (bool, bool) { return name == "Other", false }
+func (infoClient) Descriptors(name string) (input, output protoreflect.MessageDescriptor) {
+	return (*healthpb.HealthCheckRequest)(nil).ProtoReflect().Descriptor(),
+		(*healthpb.HealthCheckResponse)(nil).ProtoReflect().Descriptor()
+}
+func (infoClient) Comments(name string) string { return " " + name + " is described.\n" }
+
+var _ MethodInfo = infoClient{}
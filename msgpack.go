@@ -0,0 +1,75 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgpackCodec is the Codec of MessagePack (application/msgpack).
+//
+// The streamed inputs and parts are concatenated, the struct fields are named by their json tags.
+// Decoding falls back to the lenient weakDecode, as JSON does.
+type MsgpackCodec struct{}
+
+var _ Codec = MsgpackCodec{}
+
+func (MsgpackCodec) ContentType() string { return "application/msgpack" }
+func (MsgpackCodec) NewDecoder(r io.Reader) Decoder {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return msgpackDecoder{dec: dec}
+}
+func (MsgpackCodec) NewEncoder(w io.Writer) Encoder {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	enc.SetOmitEmpty(true)
+	return msgpackEncoder{enc: enc}
+}
+
+type msgpackDecoder struct {
+	dec *msgpack.Decoder
+}
+
+func (d msgpackDecoder) Decode(v any) error {
+	raw, err := d.dec.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	// unknown fields are tried with weakDecode
+	dec := msgpack.NewDecoder(bytes.NewReader(raw))
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+	if err = dec.Decode(v); err == nil {
+		return nil
+	}
+	origErr := err
+	m := mapPool.Get().(map[string]any)
+	defer func() {
+		for k := range m {
+			delete(m, k)
+		}
+		mapPool.Put(m)
+	}()
+	dec.Reset(bytes.NewReader(raw))
+	if err = dec.Decode(&m); err != nil {
+		return fmt.Errorf("decode: %w (was: %+v)", err, origErr)
+	}
+	if err = weakDecode(m, v, false); err != nil {
+		return fmt.Errorf("%w (was: %+v)", err, origErr)
+	}
+	return nil
+}
+
+type msgpackEncoder struct {
+	enc *msgpack.Encoder
+}
+
+func (e msgpackEncoder) Encode(v any) error          { return e.enc.Encode(v) }
+func (e msgpackEncoder) EncodeError(err error) error { return e.enc.Encode(newErrorValue(err)) }
@@ -0,0 +1,274 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// OpenAPIHandler serves the OpenAPI 3.1 document of the Client's functions, as served by JSONHandler.
+type OpenAPIHandler struct {
+	Client
+	// Title and Version of the API.
+	Title, Version string
+	// Prefix is the path the JSONHandler is served under: a function is at Prefix + "/" + name.
+	Prefix string
+	// Servers are the URLs of the servers.
+	Servers []string
+	// JSON is the codec of the JSONHandler.
+	JSON JSONCodec
+}
+
+func (h OpenAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(h.Document())
+}
+
+// Document returns the OpenAPI document.
+func (h OpenAPIHandler) Document() map[string]any {
+	title, version := h.Title, h.Version
+	if title == "" {
+		title = "grpcer"
+	}
+	if version == "" {
+		version = "0"
+	}
+	g := schemaGen{defs: make(map[string]any), refPrefix: "#/components/schemas/", codec: h.JSON}
+	errorSchema := g.schema(reflect.TypeOf(ErrorBody{}))
+	errorResponse := map[string]any{
+		"description": "error",
+		"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+	}
+
+	names := h.List()
+	slices.Sort(names)
+	paths := make(map[string]any, len(names))
+	for _, name := range names {
+		inp := h.Input(name)
+		if inp == nil {
+			continue
+		}
+		d := describeMethod(h.Client, name, inp)
+		inSchema := g.inputSchema(d, inp)
+		outSchema := map[string]any{}
+		if d.output != nil {
+			outSchema = g.messageSchema(d.output)
+		}
+		op := map[string]any{
+			"operationId": name,
+			"parameters":  operationParameters(d.streaming),
+			"requestBody": map[string]any{"content": requestContent(d.streaming, inSchema)},
+			"responses": map[string]any{
+				"200":     responseOK(d.streaming, outSchema),
+				"default": errorResponse,
+			},
+		}
+		if len(d.tags) != 0 {
+			op["tags"] = d.tags
+		}
+		if d.comments != "" {
+			op["description"] = d.comments
+		}
+		paths[strings.TrimSuffix(h.Prefix, "/")+"/"+name] = map[string]any{"post": op}
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": g.defs,
+			"securitySchemes": map[string]any{
+				"basicAuth": map[string]any{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []any{map[string]any{"basicAuth": []string{}}},
+	}
+	if len(h.Servers) != 0 {
+		servers := make([]any, 0, len(h.Servers))
+		for _, s := range h.Servers {
+			servers = append(servers, map[string]any{"url": s})
+		}
+		doc["servers"] = servers
+	}
+	return doc
+}
+
+// operationParameters returns the query parameters of a method with the streaming kind.
+func operationParameters(streaming string) []any {
+	params := []any{map[string]any{
+		"name": "format", "in": "query",
+		"description": "the format of the response: Server-Sent Events, or a table of the parts",
+		"schema":      map[string]any{"type": "string", "enum": []string{"sse", "csv", "xlsx"}},
+	}}
+	if streaming != StreamingUnary && streaming != StreamingClient {
+		params = append(params, map[string]any{
+			"name": "merge", "in": "query",
+			"description": "merge the streamed parts into one object, appending the slices",
+			"schema":      map[string]any{"type": "string", "enum": []string{"0", "1"}},
+		})
+	}
+	return params
+}
+
+// requestContent returns the content of the request body of a method with the streaming kind:
+// the inputs of a client streaming method may be sent as NDJSON.
+//
+// The streaming kind of the method is unknown if empty, then JSONHandler decides by the Content-Type.
+func requestContent(streaming string, inSchema map[string]any) map[string]any {
+	content := map[string]any{"application/json": map[string]any{"schema": inSchema}}
+	if streaming == StreamingClient || streaming == StreamingBidi || streaming == "" {
+		content["application/x-ndjson"] = map[string]any{"schema": inSchema}
+	}
+	return content
+}
+
+// responseOK returns the successful response of a method with the streaming kind.
+func responseOK(streaming string, outSchema map[string]any) map[string]any {
+	description := "the part"
+	content := map[string]any{
+		"application/json":  map[string]any{"schema": outSchema},
+		"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}},
+		"text/csv":          map[string]any{"schema": map[string]any{"type": "string"}},
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": map[string]any{
+			"schema": map[string]any{"type": "string", "contentEncoding": "binary"},
+		},
+	}
+	if streaming != StreamingUnary && streaming != StreamingClient {
+		description = "the parts, one per line (or the merged part)"
+		content["application/x-ndjson"] = map[string]any{"schema": outSchema}
+	}
+	return map[string]any{"description": description, "content": content}
+}
+
+// findMethod returns the descriptor of the named method with the input's message type,
+// from the global registry - or nil if not found.
+func findMethod(name string, input any) protoreflect.MethodDescriptor {
+	m, ok := input.(proto.Message)
+	if !ok {
+		return nil
+	}
+	inName := m.ProtoReflect().Descriptor().FullName()
+	var found protoreflect.MethodDescriptor
+	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		svcs := fd.Services()
+		for i := range svcs.Len() {
+			if md := svcs.Get(i).Methods().ByName(protoreflect.Name(name)); md != nil && md.Input().FullName() == inName {
+				found = md
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	protoMessageType  = reflect.TypeOf((*proto.Message)(nil)).Elem()
+)
+
+// schemaGen generates JSON Schemas for Go types, as encoding/json encodes them,
+// and for protobuf messages, as the codec encodes them.
+type schemaGen struct {
+	defs      map[string]any
+	refPrefix string
+	codec     JSONCodec
+}
+
+// schema returns the JSON Schema of the type, named structs referenced from defs.
+func (g schemaGen) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case reflect.PointerTo(t).Implements(protoMessageType):
+		return g.messageSchema(reflect.New(t).Interface().(proto.Message).ProtoReflect().Descriptor())
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]any{}
+	case reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := strings.ReplaceAll(strings.TrimPrefix(t.String(), "*"), "/", ".")
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = nil // against recursion
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": g.refPrefix + name}
+	}
+	return map[string]any{}
+}
+
+func (g schemaGen) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range g.structSchema(ft)["properties"].(map[string]any) {
+					if _, ok := props[k]; !ok {
+						props[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+	return map[string]any{"type": "object", "properties": props}
+}
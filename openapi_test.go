@@ -0,0 +1,101 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthClient is a Client for the registered grpc.health.v1.Health service.
+type healthClient struct{}
+
+func (healthClient) List() []string { return []string{"Check", "Watch", "Other"} }
+func (healthClient) Input(name string) any {
+	switch name {
+	case "Check", "Watch":
+		return new(healthpb.HealthCheckRequest)
+	case "Other":
+		return new(struct {
+			When  time.Time `json:"when"`
+			Data  []byte
+			Skip  int      `json:"-"`
+			Names []string `json:"names,omitempty"`
+		})
+	}
+	return nil
+}
+func (healthClient) Call(name string, ctx context.Context, input any, opts ...grpc.CallOption) (Receiver, error) {
+	return nil, ErrNotFound
+}
+func (healthClient) Tags(name string) []string {
+	if name == "Check" {
+		return []string{"health"}
+	}
+	return nil
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := OpenAPIHandler{Client: healthClient{}, Prefix: "/api/", Title: "test"}.Document()
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, want := range []string{
+		`"/api/Check"`, `"/api/Watch"`, `"/api/Other"`,
+		`"$ref": "#/components/schemas/grpc.health.v1.HealthCheckResponse"`,
+		`"grpc.health.v1.HealthCheckRequest"`, `"SERVICE_UNKNOWN"`,
+		`"when": {`, `"format": "date-time"`,
+		`"names": {`,
+		`"contentEncoding": "base64"`,
+		`"health"`,
+		`"scheme": "basic"`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("%q not found", want)
+		}
+	}
+	if strings.Contains(s, `"Skip"`) {
+		t.Error("Skip found")
+	}
+
+	// the parameters and media types follow the streaming kind
+	paths := doc["paths"].(map[string]any)
+	for _, tC := range []struct {
+		Name                       string
+		Merge, NDJSONIn, NDJSONOut bool
+	}{
+		{Name: "Check"},
+		{Name: "Watch", Merge: true, NDJSONOut: true},
+		{Name: "Other", Merge: true, NDJSONIn: true, NDJSONOut: true},
+	} {
+		op := paths["/api/"+tC.Name].(map[string]any)["post"].(map[string]any)
+		var params []string
+		for _, p := range op["parameters"].([]any) {
+			params = append(params, p.(map[string]any)["name"].(string))
+		}
+		if got := strings.Join(params, ","); got != map[bool]string{false: "format", true: "format,merge"}[tC.Merge] {
+			t.Errorf("%s: got parameters %s", tC.Name, got)
+		}
+		in := op["requestBody"].(map[string]any)["content"].(map[string]any)
+		if _, ok := in["application/x-ndjson"]; ok != tC.NDJSONIn {
+			t.Errorf("%s: NDJSON request body: got %t, wanted %t", tC.Name, ok, tC.NDJSONIn)
+		}
+		out := op["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+		if _, ok := out["application/x-ndjson"]; ok != tC.NDJSONOut {
+			t.Errorf("%s: NDJSON response: got %t, wanted %t", tC.Name, ok, tC.NDJSONOut)
+		}
+		if _, ok := out["text/event-stream"]; !ok {
+			t.Errorf("%s: no SSE response", tC.Name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtobufCodec is the Codec of the binary protobuf encoding (application/x-protobuf),
+// for proto.Message inputs and outputs only.
+//
+// The request body is one message, the parts are written length-delimited (as protodelim),
+// and an error as a final google.rpc.Status message (with a non-zero Grpc-Status trailer).
+type ProtobufCodec struct{}
+
+var _ Codec = ProtobufCodec{}
+
+func (ProtobufCodec) ContentType() string            { return "application/x-protobuf" }
+func (ProtobufCodec) NewDecoder(r io.Reader) Decoder { return &protobufDecoder{r: r} }
+func (ProtobufCodec) NewEncoder(w io.Writer) Encoder { return protobufEncoder{w: w} }
+
+// Marshal the proto.Message.
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal b into the proto.Message.
+func (ProtobufCodec) Unmarshal(b []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(b, m)
+}
+
+type protobufDecoder struct {
+	r    io.Reader
+	read bool
+}
+
+func (d *protobufDecoder) Decode(v any) error {
+	if d.read {
+		return io.EOF
+	}
+	d.read = true
+	b, err := readAllLimit(d.r, maxFrameSize)
+	if err != nil {
+		return err
+	}
+	return ProtobufCodec{}.Unmarshal(b, v)
+}
+
+// readAllLimit reads r till EOF, returning an error if it is longer than limit.
+func readAllLimit(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return b, err
+	}
+	if int64(len(b)) > limit {
+		return nil, status.Errorf(codes.InvalidArgument, "message is bigger than %d", limit)
+	}
+	return b, nil
+}
+
+type protobufEncoder struct {
+	w io.Writer
+}
+
+func (e protobufEncoder) Encode(v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	_, err := protodelim.MarshalTo(e.w, m)
+	return err
+}
+func (e protobufEncoder) EncodeError(err error) error {
+	st, _ := grpcStatus(err)
+	return e.Encode(st.Proto())
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// watchClient streams SERVING and NOT_SERVING for Watch (only SERVING for Check),
+// then fails if the service is "fail".
+type watchClient struct{ healthClient }
+
+func (watchClient) Call(name string, ctx context.Context, input any, opts ...grpc.CallOption) (Receiver, error) {
+	recv := &receiver{parts: []any{
+		&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING},
+		&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING},
+	}}
+	if name == "Check" {
+		recv.parts = recv.parts[:1]
+	}
+	if input.(*healthpb.HealthCheckRequest).GetService() == "fail" {
+		recv.err = grpcstatus.Error(codes.Unavailable, "failed")
+	}
+	return recv, nil
+}
+
+func TestProtobufCodec(t *testing.T) {
+	h := JSONHandler{Client: watchClient{}, Logger: zlog.NewT(t).SLog()}
+	for _, service := range []string{"ok", "fail"} {
+		b, err := proto.Marshal(&healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("POST", "/Watch", bytes.NewReader(b))
+		r.Header.Set("Content-Type", "application/x-protobuf")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 200 || w.Header().Get("Content-Type") != "application/x-protobuf" {
+			t.Fatalf("%s: got %d %q: %q", service, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		br := bufio.NewReader(w.Body)
+		for _, want := range []healthpb.HealthCheckResponse_ServingStatus{
+			healthpb.HealthCheckResponse_SERVING, healthpb.HealthCheckResponse_NOT_SERVING,
+		} {
+			var resp healthpb.HealthCheckResponse
+			if err := protodelim.UnmarshalFrom(br, &resp); err != nil {
+				t.Fatalf("%s: %+v", service, err)
+			}
+			if resp.GetStatus() != want {
+				t.Errorf("%s: got %v, wanted %v", service, resp.GetStatus(), want)
+			}
+		}
+		if service == "fail" {
+			var st status.Status
+			if err := protodelim.UnmarshalFrom(br, &st); err != nil {
+				t.Fatalf("%s: %+v", service, err)
+			}
+			if codes.Code(st.GetCode()) != codes.Unavailable || st.GetMessage() != "failed" {
+				t.Errorf("%s: got %v", service, &st)
+			}
+			if got := w.Result().Trailer.Get("Grpc-Status"); got != "14" {
+				t.Errorf("%s: got Grpc-Status %q", service, got)
+			}
+		}
+		if br.Buffered() != 0 {
+			t.Errorf("%s: %d bytes remained", service, br.Buffered())
+		}
+	}
+}
+
+func TestProtobufBadInput(t *testing.T) {
+	h := JSONHandler{Client: watchClient{}, Logger: zlog.NewT(t).SLog()}
+	r := httptest.NewRequest("POST", "/Check", bytes.NewReader([]byte{0xff, 0xff, 0xff}))
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 400 {
+		t.Errorf("got %d, wanted 400", w.Code)
+	}
+
+	if _, err := readAllLimit(bytes.NewReader(make([]byte, 11)), 10); grpcstatus.Code(err) != codes.InvalidArgument {
+		t.Errorf("got %+v, wanted InvalidArgument", err)
+	}
+	if b, err := readAllLimit(bytes.NewReader(make([]byte, 10)), 10); err != nil || len(b) != 10 {
+		t.Errorf("got %d, %+v", len(b), err)
+	}
+}
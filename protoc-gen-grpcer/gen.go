@@ -72,9 +72,11 @@ func Main(p *protogen.Plugin) error {
 
 	for _, root := range roots {
 		pkg := root.GetName()
-		for _, svc := range root.GetService() {
+		for i, svc := range root.GetService() {
 			destFn := strings.TrimSuffix(filepath.Base(pkg), ".proto") + ".grpcer.go"
-			if err := genGo(p.NewGeneratedFile(destFn, protogen.GoImportPath(pkg)), destPkg, pkg, svc, root.GetDependency()); err != nil {
+			if err := genGo(p.NewGeneratedFile(destFn, protogen.GoImportPath(pkg)), destPkg, pkg, svc, root.GetDependency(),
+				root.GetPackage(), methodComments(root, int32(i)),
+			); err != nil {
 				p.Error(err)
 			}
 		}
@@ -103,6 +105,26 @@ func getTags(m *descriptorpb.MethodDescriptorProto) []string {
 	}
 	return tags
 }
+
+// methodComments returns the leading comments of the methods of the i-th service of the file, by method name.
+func methodComments(f *descriptorpb.FileDescriptorProto, i int32) map[string]string {
+	methods := f.GetService()[i].GetMethod()
+	comments := make(map[string]string, len(methods))
+	for _, loc := range f.GetSourceCodeInfo().GetLocation() {
+		// service = 6, method = 2 in descriptor.proto
+		if path := loc.GetPath(); len(path) == 4 && path[0] == 6 && path[1] == i && path[2] == 2 &&
+			loc.GetLeadingComments() != "" && int(path[3]) < len(methods) {
+			comments[methods[path[3]].GetName()] = loc.GetLeadingComments()
+		}
+	}
+	return comments
+}
+func fullMethod(protoPkg string, svc *descriptorpb.ServiceDescriptorProto, m *descriptorpb.MethodDescriptorProto) string {
+	if protoPkg != "" {
+		protoPkg += "."
+	}
+	return "/" + protoPkg + svc.GetName() + "/" + m.GetName()
+}
 func trimLeftDot(s string) string { return strings.TrimLeft(s, ".") }
 func changePkgTo(from, to, what string) string {
 	if j := strings.LastIndexByte(from, '/'); j >= 0 {
@@ -121,7 +143,7 @@ func changePkgTo(from, to, what string) string {
 	return to + what[i:]
 }
 
-func genGo(w io.Writer, destPkg, protoFn string, svc *descriptorpb.ServiceDescriptorProto, dependencies []string) error {
+func genGo(w io.Writer, destPkg, protoFn string, svc *descriptorpb.ServiceDescriptorProto, dependencies []string, protoPkg string, comments map[string]string) error {
 	if destPkg == "" {
 		destPkg = "main"
 	}
@@ -149,14 +171,17 @@ func genGo(w io.Writer, destPkg, protoFn string, svc *descriptorpb.ServiceDescri
 		Package:      destPkg,
 		Import:       filepath.Dir(protoFn),
 		Dependencies: deps,
+		ProtoPackage: protoPkg,
+		Comments:     comments,
 	})
 	quicktemplate.ReleaseWriter(W)
 	return nil
 }
 
 type FileInfo struct {
-	ProtoFile, Package, Import string
-	Dependencies               []string
+	Comments                                 map[string]string
+	ProtoFile, Package, Import, ProtoPackage string
+	Dependencies                             []string
 }
 
 // vim: set fileencoding=utf-8 noet:
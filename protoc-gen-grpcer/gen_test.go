@@ -5,6 +5,7 @@
 package main_test
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"os/exec"
@@ -30,4 +31,50 @@ func TestGen(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%s: %+v", b, err)
 	}
+
+	// Compile the generated client with the generated pb package,
+	// in a "testdata" module that uses this grpcer.
+	for _, nm := range []string{"protoc-gen-go", "protoc-gen-go-grpc"} {
+		if _, err := exec.LookPath(nm); err != nil {
+			t.Skipf("%s: %+v", nm, err)
+		}
+	}
+	dir := t.TempDir()
+	root := filepath.Dir(cwd)
+	if b, err = os.ReadFile(filepath.Join(root, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	_, b, _ = bytes.Cut(b, []byte("\n"))
+	b = append(append([]byte("module testdata\n"), b...), "\nrequire github.com/UNO-SOFT/grpcer v0.0.0\nreplace github.com/UNO-SOFT/grpcer => "+root+"\n"...)
+	if err = os.WriteFile(filepath.Join(dir, "go.mod"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = os.ReadFile(filepath.Join(root, "go.sum")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "go.sum"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// protoc-gen-go needs a slash in the import path, but it is not used in the generated code.
+	const opts = "module=x/testdata,Mtestdata/db_pgw_ws.proto=x/testdata:"
+	cmd = exec.CommandContext(ctx, "protoc", "--go_out="+opts+dir, "--go-grpc_out="+opts+dir,
+		"-I", os.ExpandEnv("$HOME/src:."), "./testdata/db_pgw_ws.proto")
+	if b, err = cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %+v", b, err)
+	}
+	if b, err = os.ReadFile(filepath.Join(cwd, "testdata", "db_pgw_ws.grpcer.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Mkdir(filepath.Join(dir, "dij"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "dij", "db_pgw_ws.grpcer.go"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.CommandContext(ctx, "go", "vet", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if b, err = cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %+v", b, err)
+	}
 }
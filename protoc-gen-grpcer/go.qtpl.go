@@ -1,50 +1,50 @@
 // Code generated by qtc from "go.qtpl". DO NOT EDIT.
 // See https://github.com/valyala/quicktemplate for details.
 
-//line protoc-gen-grpcer/go.qtpl:1
+//line go.qtpl:1
 package main
 
-//line protoc-gen-grpcer/go.qtpl:3
+//line go.qtpl:3
 import "time"
 
-//line protoc-gen-grpcer/go.qtpl:4
+//line go.qtpl:4
 import "google.golang.org/protobuf/types/descriptorpb"
 
-//line protoc-gen-grpcer/go.qtpl:6
+//line go.qtpl:6
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line protoc-gen-grpcer/go.qtpl:6
+//line go.qtpl:6
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line protoc-gen-grpcer/go.qtpl:6
+//line go.qtpl:6
 func StreamXGo(qw422016 *qt422016.Writer, svc *descriptorpb.ServiceDescriptorProto, info FileInfo) {
-//line protoc-gen-grpcer/go.qtpl:6
+//line go.qtpl:6
 	qw422016.N().S(`
 // Generated with protoc-gen-grpcer
 //	from `)
-//line protoc-gen-grpcer/go.qtpl:8
+//line go.qtpl:8
 	qw422016.N().Q(info.ProtoFile)
-//line protoc-gen-grpcer/go.qtpl:8
+//line go.qtpl:8
 	qw422016.N().S(`
 //	at   `)
-//line protoc-gen-grpcer/go.qtpl:9
+//line go.qtpl:9
 	qw422016.N().S(time.Now().String())
-//line protoc-gen-grpcer/go.qtpl:9
+//line go.qtpl:9
 	qw422016.N().S(`
 //
 // DO NOT EDIT!
 
 package `)
-//line protoc-gen-grpcer/go.qtpl:13
+//line go.qtpl:13
 	qw422016.N().S(info.Package)
-//line protoc-gen-grpcer/go.qtpl:13
+//line go.qtpl:13
 	qw422016.N().S(`
 
 import (
@@ -54,31 +54,32 @@ import (
 
 	grpc "google.golang.org/grpc"
 	grpcer "github.com/UNO-SOFT/grpcer"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 
 	pb `)
-//line protoc-gen-grpcer/go.qtpl:23
+//line go.qtpl:24
 	qw422016.N().Q(info.Import)
-//line protoc-gen-grpcer/go.qtpl:23
+//line go.qtpl:24
 	qw422016.N().S(`
 	`)
-//line protoc-gen-grpcer/go.qtpl:24
+//line go.qtpl:25
 	for _, dep := range info.Dependencies {
-//line protoc-gen-grpcer/go.qtpl:24
+//line go.qtpl:25
 		qw422016.N().Q(dep)
-//line protoc-gen-grpcer/go.qtpl:24
+//line go.qtpl:25
 		qw422016.N().S(`
 	`)
-//line protoc-gen-grpcer/go.qtpl:25
+//line go.qtpl:26
 	}
-//line protoc-gen-grpcer/go.qtpl:25
+//line go.qtpl:26
 	qw422016.N().S(`
 )
 
 type client struct {
 	pb.`)
-//line protoc-gen-grpcer/go.qtpl:29
+//line go.qtpl:30
 	qw422016.N().S(svc.GetName())
-//line protoc-gen-grpcer/go.qtpl:29
+//line go.qtpl:30
 	qw422016.N().S(`Client
 	m map[string]inputAndCall
 }
@@ -107,104 +108,307 @@ func (c client) Call(name string, ctx context.Context, in interface{}, opts ...g
 	return iac.Call(ctx, in, opts...)
 }
 
+func (c client) CallStream(name string, ctx context.Context, opts ...grpc.CallOption) (grpcer.Stream, error) {
+	iac := c.m[name]
+	if iac.Stream != nil {
+		return iac.Stream(ctx, opts...)
+	}
+	if iac.Call == nil {
+		return nil, fmt.Errorf("name %q not found", name)
+	}
+	return grpcer.NewUnaryStream(ctx, func(in interface{}) (grpcer.Receiver, error) {
+		return iac.Call(ctx, in, opts...)
+	}), nil
+}
+
 func (c client) Tags(name string) []string { return c.m[name].Tags }
+func (c client) FullMethod(name string) string { return c.m[name].FullMethod }
+func (c client) Streaming(name string) (clientStreaming, serverStreaming bool) {
+	iac := c.m[name]
+	return iac.ClientStreaming, iac.ServerStreaming
+}
+func (c client) Descriptors(name string) (input, output protoreflect.MessageDescriptor) {
+	iac := c.m[name]
+	return iac.InputDesc, iac.OutputDesc
+}
+func (c client) Comments(name string) string { return c.m[name].Comments }
+
+var _ grpcer.MethodInfo = client{}
 
-func NewClient(cc *grpc.ClientConn) grpcer.Client {
+func NewClient(cc *grpc.ClientConn) grpcer.StreamClient {
 	c := pb.New`)
-//line protoc-gen-grpcer/go.qtpl:60
+//line go.qtpl:86
 	qw422016.N().S(svc.GetName())
-//line protoc-gen-grpcer/go.qtpl:60
+//line go.qtpl:86
 	qw422016.N().S(`Client(cc)
 	return client{
 		`)
-//line protoc-gen-grpcer/go.qtpl:62
+//line go.qtpl:88
 	qw422016.N().S(svc.GetName())
-//line protoc-gen-grpcer/go.qtpl:62
+//line go.qtpl:88
 	qw422016.N().S(`Client: c,
 		m: map[string]inputAndCall{
 		`)
-//line protoc-gen-grpcer/go.qtpl:64
+//line go.qtpl:90
 	for _, m := range svc.GetMethod() {
-//line protoc-gen-grpcer/go.qtpl:64
+//line go.qtpl:90
 		qw422016.N().Q(m.GetName())
-//line protoc-gen-grpcer/go.qtpl:64
+//line go.qtpl:90
 		qw422016.N().S(`: inputAndCall{
 			Input: func() interface{} { return new(`)
-//line protoc-gen-grpcer/go.qtpl:65
-		qw422016.N().S(changePkgTo(info.Import, "pb", trimLeftDot(m.GetInputType())))
-//line protoc-gen-grpcer/go.qtpl:65
+//line go.qtpl:91
+		qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetInputType())))
+//line go.qtpl:91
 		qw422016.N().S(`) },
 			Call: func(ctx context.Context, in interface{}, opts ...grpc.CallOption) (grpcer.Receiver, error) {
 				input := in.(*`)
-//line protoc-gen-grpcer/go.qtpl:67
-		qw422016.N().S(changePkgTo(info.Import, "pb", trimLeftDot(m.GetInputType())))
-//line protoc-gen-grpcer/go.qtpl:67
+//line go.qtpl:93
+		qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetInputType())))
+//line go.qtpl:93
 		qw422016.N().S(`)
+				`)
+//line go.qtpl:94
+		if m.GetClientStreaming() {
+//line go.qtpl:94
+			qw422016.N().S(`
+				stream, err := c.`)
+//line go.qtpl:95
+			qw422016.N().S(m.GetName())
+//line go.qtpl:95
+			qw422016.N().S(`(ctx, opts...)
+				if err != nil {
+					return nil, err
+				}
+				if err = stream.Send(input); err != nil && err != io.EOF {
+					return nil, err
+				}
+				// on io.EOF the stream has failed: its status is returned by the receiving
+				`)
+//line go.qtpl:103
+			if m.GetServerStreaming() {
+//line go.qtpl:104
+				qw422016.N().S(` if err = stream.CloseSend(); err != nil {
+					return nil, err
+				}
+				return multiRecv(func() (interface{}, error) { return stream.Recv() }), nil
+				`)
+//line go.qtpl:109
+			} else {
+//line go.qtpl:109
+				qw422016.N().S(` res, err := stream.CloseAndRecv()
+				return &onceRecv{Out:res}, err
+				`)
+//line go.qtpl:111
+			}
+//line go.qtpl:111
+			qw422016.N().S(`
+				`)
+//line go.qtpl:112
+		} else {
+//line go.qtpl:112
+			qw422016.N().S(`
 				res, err := c.`)
-//line protoc-gen-grpcer/go.qtpl:68
-		qw422016.N().S(m.GetName())
-//line protoc-gen-grpcer/go.qtpl:68
-		qw422016.N().S(`(ctx, input, opts...)
+//line go.qtpl:113
+			qw422016.N().S(m.GetName())
+//line go.qtpl:113
+			qw422016.N().S(`(ctx, input, opts...)
 				if err != nil {
 					return &onceRecv{Out:res}, err
 				}
 				`)
-//line protoc-gen-grpcer/go.qtpl:72
-		if m.GetServerStreaming() {
-//line protoc-gen-grpcer/go.qtpl:73
-			qw422016.N().S(` return multiRecv(func() (interface{}, error) { return res.Recv() }), nil
+//line go.qtpl:117
+			if m.GetServerStreaming() {
+//line go.qtpl:118
+				qw422016.N().S(` return multiRecv(func() (interface{}, error) { return res.Recv() }), nil
 				`)
-//line protoc-gen-grpcer/go.qtpl:75
-		} else {
-//line protoc-gen-grpcer/go.qtpl:75
-			qw422016.N().S(` return &onceRecv{Out:res}, err
+//line go.qtpl:120
+			} else {
+//line go.qtpl:120
+				qw422016.N().S(` return &onceRecv{Out:res}, err
 				`)
-//line protoc-gen-grpcer/go.qtpl:76
+//line go.qtpl:121
+			}
+//line go.qtpl:121
+			qw422016.N().S(`
+				`)
+//line go.qtpl:122
 		}
-//line protoc-gen-grpcer/go.qtpl:76
+//line go.qtpl:122
 		qw422016.N().S(`
 			},
 			`)
-//line protoc-gen-grpcer/go.qtpl:78
+//line go.qtpl:124
+		if m.GetClientStreaming() {
+//line go.qtpl:124
+			qw422016.N().S(`Stream: func(ctx context.Context, opts ...grpc.CallOption) (grpcer.Stream, error) {
+				stream, err := c.`)
+//line go.qtpl:125
+			qw422016.N().S(m.GetName())
+//line go.qtpl:125
+			qw422016.N().S(`(ctx, opts...)
+				if err != nil {
+					return nil, err
+				}
+				return streamFuncs{
+					send: func(in interface{}) error {
+						return stream.Send(in.(*`)
+//line go.qtpl:131
+			qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetInputType())))
+//line go.qtpl:131
+			qw422016.N().S(`))
+					},
+					closeSend: stream.CloseSend,
+					`)
+//line go.qtpl:134
+			if m.GetServerStreaming() {
+//line go.qtpl:135
+				qw422016.N().S(`recv: func() (interface{}, error) { return stream.Recv() },
+					`)
+//line go.qtpl:137
+			} else {
+//line go.qtpl:137
+				qw422016.N().S(`recv: (&onceRecv{Call: func() (interface{}, error) {
+						out := new(`)
+//line go.qtpl:138
+				qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetOutputType())))
+//line go.qtpl:138
+				qw422016.N().S(`)
+						err := stream.RecvMsg(out)
+						return out, err
+					}}).Recv,
+					`)
+//line go.qtpl:142
+			}
+//line go.qtpl:142
+			qw422016.N().S(`
+				}, nil
+			},
+			`)
+//line go.qtpl:145
+		}
+//line go.qtpl:145
+		qw422016.N().S(`
+			FullMethod: `)
+//line go.qtpl:146
+		qw422016.N().Q(fullMethod(info.ProtoPackage, svc, m))
+//line go.qtpl:146
+		qw422016.N().S(`,
+			`)
+//line go.qtpl:147
+		if m.GetClientStreaming() {
+//line go.qtpl:147
+			qw422016.N().S(`ClientStreaming: true,`)
+//line go.qtpl:147
+		}
+//line go.qtpl:147
+		qw422016.N().S(`
+			`)
+//line go.qtpl:148
+		if m.GetServerStreaming() {
+//line go.qtpl:148
+			qw422016.N().S(`ServerStreaming: true,`)
+//line go.qtpl:148
+		}
+//line go.qtpl:148
+		qw422016.N().S(`
+			InputDesc: (*`)
+//line go.qtpl:149
+		qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetInputType())))
+//line go.qtpl:149
+		qw422016.N().S(`)(nil).ProtoReflect().Descriptor(),
+			OutputDesc: (*`)
+//line go.qtpl:150
+		qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetOutputType())))
+//line go.qtpl:150
+		qw422016.N().S(`)(nil).ProtoReflect().Descriptor(),
+			`)
+//line go.qtpl:151
+		if c := info.Comments[m.GetName()]; c != "" {
+//line go.qtpl:151
+			qw422016.N().S(`Comments: `)
+//line go.qtpl:151
+			qw422016.N().Q(c)
+//line go.qtpl:151
+			qw422016.N().S(`,`)
+//line go.qtpl:151
+		}
+//line go.qtpl:151
+		qw422016.N().S(`
+			`)
+//line go.qtpl:152
 		if tags := getTags(m); len(tags) != 0 {
-//line protoc-gen-grpcer/go.qtpl:79
+//line go.qtpl:153
 			qw422016.N().S(`Tags: []string{ `)
-//line protoc-gen-grpcer/go.qtpl:79
+//line go.qtpl:153
 			for i, t := range tags {
-//line protoc-gen-grpcer/go.qtpl:79
+//line go.qtpl:153
 				if i != 0 {
-//line protoc-gen-grpcer/go.qtpl:79
+//line go.qtpl:153
 					qw422016.N().S(`, `)
-//line protoc-gen-grpcer/go.qtpl:80
+//line go.qtpl:154
 				}
-//line protoc-gen-grpcer/go.qtpl:80
+//line go.qtpl:154
 				qw422016.N().Q(t)
-//line protoc-gen-grpcer/go.qtpl:80
+//line go.qtpl:154
 			}
-//line protoc-gen-grpcer/go.qtpl:80
+//line go.qtpl:154
 			qw422016.N().S(` },`)
-//line protoc-gen-grpcer/go.qtpl:80
+//line go.qtpl:154
 		}
-//line protoc-gen-grpcer/go.qtpl:80
+//line go.qtpl:154
 		qw422016.N().S(`
 		},
 		`)
-//line protoc-gen-grpcer/go.qtpl:82
+//line go.qtpl:156
 	}
-//line protoc-gen-grpcer/go.qtpl:82
+//line go.qtpl:156
 	qw422016.N().S(`
 		},
 	}
 }
 
+// The typed accessors of the methods.
+var (
+`)
+//line go.qtpl:163
+	for _, m := range svc.GetMethod() {
+//line go.qtpl:163
+		qw422016.N().S(`	Method`)
+//line go.qtpl:163
+		qw422016.N().S(m.GetName())
+//line go.qtpl:163
+		qw422016.N().S(` = grpcer.Method[*`)
+//line go.qtpl:163
+		qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetInputType())))
+//line go.qtpl:163
+		qw422016.N().S(`, *`)
+//line go.qtpl:163
+		qw422016.N().S(changePkgTo(info.ProtoPackage, "pb", trimLeftDot(m.GetOutputType())))
+//line go.qtpl:163
+		qw422016.N().S(`]{Name: `)
+//line go.qtpl:163
+		qw422016.N().Q(m.GetName())
+//line go.qtpl:163
+		qw422016.N().S(`}
+`)
+//line go.qtpl:164
+	}
+//line go.qtpl:164
+	qw422016.N().S(`)
+
 type inputAndCall struct {
 	Input func() interface{}
 	Call func(ctx context.Context, in interface{}, opts ...grpc.CallOption) (grpcer.Receiver, error)
+	Stream func(ctx context.Context, opts ...grpc.CallOption) (grpcer.Stream, error)
+	InputDesc, OutputDesc protoreflect.MessageDescriptor
+	FullMethod, Comments string
 	Tags []string
+	ClientStreaming, ServerStreaming bool
 }
 
 type onceRecv struct {
 	Out interface{}
+	Call func() (interface{}, error)
 	done bool
 }
 func (o *onceRecv) Recv() (interface{}, error) {
@@ -213,6 +417,9 @@ func (o *onceRecv) Recv() (interface{}, error) {
 	}
 	out := o.Out
 	o.done, o.Out = true, nil
+	if o.Call != nil {
+		return o.Call()
+	}
 	return out, nil
 }
 
@@ -221,33 +428,43 @@ func (m multiRecv) Recv() (interface{}, error) {
 	return m()
 }
 
+type streamFuncs struct {
+	send func(interface{}) error
+	closeSend func() error
+	recv func() (interface{}, error)
+}
+func (s streamFuncs) Send(in interface{}) error { return s.send(in) }
+func (s streamFuncs) CloseSend() error { return s.closeSend() }
+func (s streamFuncs) Recv() (interface{}, error) { return s.recv() }
+
 var _ = multiRecv(nil) // against "unused"
+var _ = streamFuncs{} // against "unused"
 `)
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 }
 
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 func WriteXGo(qq422016 qtio422016.Writer, svc *descriptorpb.ServiceDescriptorProto, info FileInfo) {
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	StreamXGo(qw422016, svc, info)
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	qt422016.ReleaseWriter(qw422016)
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 }
 
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 func XGo(svc *descriptorpb.ServiceDescriptorProto, info FileInfo) string {
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	qb422016 := qt422016.AcquireByteBuffer()
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	WriteXGo(qb422016, svc, info)
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	qs422016 := string(qb422016.B)
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	qt422016.ReleaseByteBuffer(qb422016)
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 	return qs422016
-//line protoc-gen-grpcer/go.qtpl:112
+//line go.qtpl:209
 }
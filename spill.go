@@ -0,0 +1,85 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bytes"
+	"expvar"
+	"io"
+)
+
+// DefaultSpillLimit is the default in-memory limit of a SpillBuffer.
+const DefaultSpillLimit = 1 << 20
+
+// spillStats are the metrics of the SpillBuffers, published as "grpcer.spill" by expvar:
+// the number of buffers, of the spills to disk, and of the bytes written to disk.
+var spillStats = expvar.NewMap("grpcer.spill")
+
+// SpillBuffer keeps the written data in memory up to Limit bytes,
+// and spills it to a compressed TempFile beyond it.
+type SpillBuffer struct {
+	file *TempFile
+	buf  bytes.Buffer
+	// Dir is the directory of the TempFile (os.TempDir if empty).
+	Dir string
+	// Name is the pattern of the TempFile's name, as for os.CreateTemp.
+	Name string
+	// Limit is the maximum number of bytes kept in memory (DefaultSpillLimit if 0, none if negative).
+	Limit int
+}
+
+// NewSpillBuffer returns a new SpillBuffer.
+func NewSpillBuffer(dir, name string, limit int) *SpillBuffer {
+	spillStats.Add("buffers", 1)
+	return &SpillBuffer{Dir: dir, Name: name, Limit: limit}
+}
+
+// Spilled reports whether the data has been spilled to disk.
+func (b *SpillBuffer) Spilled() bool { return b.file != nil }
+
+func (b *SpillBuffer) Write(p []byte) (int, error) {
+	if b.file != nil {
+		spillStats.Add("bytes", int64(len(p)))
+		return b.file.Write(p)
+	}
+	limit := b.Limit
+	if limit == 0 {
+		limit = DefaultSpillLimit
+	}
+	if b.buf.Len()+len(p) <= limit {
+		return b.buf.Write(p)
+	}
+	fh, err := NewTempFile(b.Dir, b.Name)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = fh.Write(b.buf.Bytes()); err != nil {
+		fh.Close()
+		return 0, err
+	}
+	spillStats.Add("spills", 1)
+	spillStats.Add("bytes", int64(b.buf.Len()+len(p)))
+	b.file = fh
+	b.buf = bytes.Buffer{}
+	return fh.Write(p)
+}
+
+// GetReader finishes the writing, and returns an io.ReadCloser for reading the data back.
+func (b *SpillBuffer) GetReader() (io.ReadCloser, error) {
+	if b.file != nil {
+		return b.file.GetReader()
+	}
+	return io.NopCloser(bytes.NewReader(b.buf.Bytes())), nil
+}
+
+// Close releases the memory and the TempFile.
+func (b *SpillBuffer) Close() error {
+	b.buf = bytes.Buffer{}
+	if fh := b.file; fh != nil {
+		b.file = nil
+		return fh.Close()
+	}
+	return nil
+}
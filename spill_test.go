@@ -0,0 +1,62 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"bytes"
+	"expvar"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	dir := t.TempDir()
+	spills := func() int64 {
+		if v, ok := spillStats.Get("spills").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	for tN, tC := range map[string]struct {
+		Limit   int
+		Spilled bool
+	}{
+		"default":  {},
+		"memory":   {Limit: 100},
+		"spill":    {Limit: 10, Spilled: true},
+		"negative": {Limit: -1, Spilled: true},
+	} {
+		before := spills()
+		b := NewSpillBuffer(dir, "spill-*.zst", tC.Limit)
+		var want bytes.Buffer
+		for range 8 {
+			io.WriteString(b, "abcdef")
+			want.WriteString("abcdef")
+		}
+		if got := b.Spilled(); got != tC.Spilled {
+			t.Errorf("%s: spilled=%t, wanted %t", tN, got, tC.Spilled)
+		}
+		if tC.Spilled && spills() != before+1 {
+			t.Errorf("%s: got %d spills, wanted %d", tN, spills(), before+1)
+		}
+		rc, err := b.GetReader()
+		if err != nil {
+			t.Fatalf("%s: %+v", tN, err)
+		}
+		var got strings.Builder
+		_, err = io.Copy(&got, rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %+v", tN, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%s: got %q, wanted %q", tN, got.String(), want.String())
+		}
+		if err = b.Close(); err != nil {
+			t.Errorf("%s: close: %+v", tN, err)
+		}
+	}
+}
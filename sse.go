@@ -0,0 +1,102 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultKeepAlive is the default period of the keep-alive comments of the Server-Sent Events stream.
+var DefaultKeepAlive = 15 * time.Second
+
+// wantsSSE reports whether the response should be a Server-Sent Events stream
+// (?format=sse or Accept: text/event-stream).
+func wantsSSE(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "sse"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// serveSSE writes each received part as a "data:" event, flushing after each,
+// and finishes the stream with an "end" or an "error" event.
+func (h JSONHandler) serveSSE(ctx context.Context, w http.ResponseWriter, first any, recv Receiver, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	rc := http.NewResponseController(w)
+
+	results := Chan(ctx, recv, 0)
+
+	keepAlive := h.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	var tick <-chan time.Time
+	if keepAlive > 0 {
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	writeEvent := func(event string, data any) error {
+		if event != "" {
+			io.WriteString(w, "event: "+event+"\n")
+		}
+		io.WriteString(w, "data: ")
+		if err := h.JSON.Encode(w, data); err != nil {
+			return err
+		}
+		io.WriteString(w, "\n")
+		return rc.Flush()
+	}
+	writeError := func(err error) {
+		logger.Error("recv", "error", err)
+		if err := writeEvent("error", NewErrorBody(err)); err != nil {
+			logger.Error("write error event", "error", err)
+		}
+	}
+
+	if err := writeEvent("", first); err != nil {
+		logger.Error("write", "error", err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			writeError(context.Cause(ctx))
+			return
+		case <-tick:
+			io.WriteString(w, ": keep-alive\n\n")
+			if err := rc.Flush(); err != nil {
+				logger.Error("flush", "error", err)
+				return
+			}
+		case res, ok := <-results:
+			if !ok {
+				if ctx.Err() != nil {
+					writeError(context.Cause(ctx))
+				} else if err := writeEvent("end", struct{}{}); err != nil {
+					logger.Error("write end event", "error", err)
+				}
+				return
+			}
+			if res.Err != nil {
+				writeError(res.Err)
+				return
+			}
+			if err := writeEvent("", res.Part); err != nil {
+				logger.Error("write", "error", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"google.golang.org/grpc"
+)
+
+// All returns an iterator over the parts received from recv.
+//
+// The iteration ends at io.EOF, or after yielding the first error.
+func All(recv Receiver) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		for {
+			part, err := recv.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(nil, err)
+				}
+				return
+			}
+			if !yield(part, nil) {
+				return
+			}
+		}
+	}
+}
+
+// prepend returns an iterator yielding the part first, then the ones of seq.
+func prepend(part any, seq iter.Seq2[any, error]) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		if yield(part, nil) {
+			seq(yield)
+		}
+	}
+}
+
+// Seq returns an iterator over the parts received from recv.
+//
+// The iteration ends at io.EOF, or after yielding the first error
+// (including ErrWrongType for a part which is not a T).
+func Seq[T any](recv Receiver) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for part, err := range All(recv) {
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			t, ok := part.(T)
+			if !ok {
+				yield(zero, fmt.Errorf("got %T, wanted %T: %w", part, zero, ErrWrongType))
+				return
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Result is a part received from a Receiver, or the error of receiving.
+type Result struct {
+	Part any
+	Err  error
+}
+
+// Chan receives the parts from recv in a new goroutine, and sends them on the returned channel,
+// which has a buffer of size parts: the receiving blocks while the buffer is full.
+//
+// The channel is closed after io.EOF, the first error (sent as the last Result),
+// or the cancelation of ctx (without sending any error).
+func Chan(ctx context.Context, recv Receiver, size int) <-chan Result {
+	ch := make(chan Result, size)
+	go func() {
+		defer close(ch)
+		for part, err := range All(recv) {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- Result{Part: part, Err: err}:
+			}
+		}
+	}()
+	return ch
+}
+
+// ChanReceiver returns a Receiver of the parts sent on ch, returning io.EOF after ch is closed,
+// the Result's Err if it is not nil, or the cause of ctx's cancelation.
+func ChanReceiver(ctx context.Context, ch <-chan Result) Receiver {
+	return chanReceiver{ctx: ctx, ch: ch}
+}
+
+type chanReceiver struct {
+	ctx context.Context
+	ch  <-chan Result
+}
+
+func (r chanReceiver) Recv() (any, error) {
+	select {
+	case <-r.ctx.Done():
+		return nil, context.Cause(r.ctx)
+	case res, ok := <-r.ch:
+		if !ok {
+			return nil, io.EOF
+		}
+		return res.Part, res.Err
+	}
+}
+
+// CallTyped calls the named function with the input, and returns an iterator over its outputs.
+func CallTyped[In, Out any](c Client, name string, ctx context.Context, input In, opts ...grpc.CallOption) (iter.Seq2[Out, error], error) {
+	recv, err := c.Call(name, ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Seq[Out](recv), nil
+}
+
+// InputTyped returns the input for the name, as an In.
+func InputTyped[In any](c Client, name string) (In, error) {
+	inp := c.Input(name)
+	in, ok := inp.(In)
+	if !ok {
+		if inp == nil {
+			return in, fmt.Errorf("no input for %q: %w", name, ErrNotFound)
+		}
+		return in, fmt.Errorf("input of %q is %T, not %T: %w", name, inp, in, ErrWrongType)
+	}
+	return in, nil
+}
+
+// Method is a typed accessor of the named function of a Client, as generated by protoc-gen-grpcer.
+type Method[In, Out any] struct {
+	Name string
+}
+
+// Input returns a new input of the method from the Client.
+func (m Method[In, Out]) Input(c Client) (In, error) { return InputTyped[In](c, m.Name) }
+
+// Call the method with the input, and return an iterator over its outputs.
+func (m Method[In, Out]) Call(c Client, ctx context.Context, input In, opts ...grpc.CallOption) (iter.Seq2[Out, error], error) {
+	return CallTyped[In, Out](c, m.Name, ctx, input, opts...)
+}
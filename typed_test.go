@@ -0,0 +1,122 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/UNO-SOFT/grpcer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMethod(t *testing.T) {
+	ctx := context.Background()
+	count := grpcer.Method[*testInput, testOutput]{Name: "Count"}
+	in, err := count.Input(testClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.A = 3
+	seq, err := count.Call(testClient{}, ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []testOutput
+	for out, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, out)
+	}
+	if len(got) != 3 || got[2].Count != 3 {
+		t.Errorf("got %+v", got)
+	}
+
+	fail := grpcer.Method[*testInput, testOutput]{Name: "Fail"}
+	seq, err = fail.Call(testClient{}, ctx, &testInput{A: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for _, err := range seq {
+		if err != nil {
+			if status.Code(err) != codes.Unavailable {
+				t.Errorf("got %+v, wanted Unavailable", err)
+			}
+			break
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("got %d outputs before the error, wanted 2", n)
+	}
+
+	// wrong output type
+	wrong, err := grpcer.CallTyped[*testInput, *testOutput](testClient{}, "Sum", ctx, &testInput{A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range wrong {
+		if !errors.Is(err, grpcer.ErrWrongType) {
+			t.Errorf("got %+v, wanted ErrWrongType", err)
+		}
+	}
+	if _, err = grpcer.InputTyped[*testOutput](testClient{}, "Sum"); !errors.Is(err, grpcer.ErrWrongType) {
+		t.Errorf("got %+v, wanted ErrWrongType", err)
+	}
+
+	if _, err = grpcer.InputTyped[*testInput](testClient{}, "unknown"); !errors.Is(err, grpcer.ErrNotFound) {
+		t.Errorf("got %+v, wanted ErrNotFound", err)
+	}
+}
+
+func TestChan(t *testing.T) {
+	ctx := context.Background()
+	c := testClient{}
+	recv, err := c.Call("Fail", ctx, &testInput{A: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []grpcer.Result
+	for res := range grpcer.Chan(ctx, recv, 1) {
+		got = append(got, res)
+	}
+	if len(got) != 3 || got[1].Err != nil || status.Code(got[2].Err) != codes.Unavailable {
+		t.Errorf("got %+v, wanted 2 parts and Unavailable", got)
+	}
+
+	if recv, err = c.Call("Count", ctx, &testInput{A: 3}); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for part, err := range grpcer.All(grpcer.ChanReceiver(ctx, grpcer.Chan(ctx, recv, 0))) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.(testOutput).Count != n+1 {
+			t.Errorf("%d. got %+v", n, part)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("got %d parts, wanted 3", n)
+	}
+
+	// canceled
+	cctx, cancel := context.WithCancel(ctx)
+	if recv, err = c.Call("Count", ctx, &testInput{A: 3}); err != nil {
+		t.Fatal(err)
+	}
+	ch := grpcer.Chan(cctx, recv, 0)
+	cancel()
+	if _, err = grpcer.ChanReceiver(cctx, ch).Recv(); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %+v, wanted Canceled", err)
+	}
+	for range ch {
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WebSocketHandler serves the Client's functions over a WebSocket.
+//
+// The client starts a call with a {"id":..., "method":"Name", "input":{...}} message,
+// sends further inputs to client-streaming and bidirectional calls with {"id":..., "input":{...}},
+// and finishes sending with {"id":..., "close":true}, or cancels the call with {"id":..., "cancel":true}.
+//
+// Each received part is sent back as {"id":..., "output":{...}},
+// and the call is finished with an {"id":..., "end":true} or an {"id":..., "error":{...}} message,
+// where the error is an ErrorBody.
+// More calls can be in flight on the same socket, distinguished by their id.
+type WebSocketHandler struct {
+	Client
+	*slog.Logger
+	GetLogger func(context.Context) *slog.Logger
+	// AcceptOptions are passed to websocket.Accept.
+	AcceptOptions *websocket.AcceptOptions
+	// JSON is the codec of the inputs and outputs.
+	JSON JSONCodec
+	// Timeout of each call.
+	Timeout time.Duration
+}
+
+type wsRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method,omitempty"`
+	Input  json.RawMessage `json:"input,omitempty"`
+	Close  bool            `json:"close,omitempty"`
+	Cancel bool            `json:"cancel,omitempty"`
+}
+
+type wsResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Output any             `json:"output,omitempty"`
+	Error  *ErrorBody      `json:"error,omitempty"`
+	End    bool            `json:"end,omitempty"`
+}
+
+type wsCall struct {
+	Stream
+	cancel context.CancelCauseFunc
+	method string
+}
+
+func (h WebSocketHandler) getLogger(ctx context.Context) *slog.Logger {
+	if h.GetLogger != nil {
+		if lgr := h.GetLogger(ctx); lgr != nil {
+			return lgr
+		}
+	}
+	return h.Logger
+}
+
+func (h WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := h.getLogger(ctx)
+	conn, err := websocket.Accept(w, r, h.AcceptOptions)
+	if err != nil {
+		logger.Error("accept", "error", err)
+		return
+	}
+	defer conn.CloseNow()
+	if u, p, ok := r.BasicAuth(); ok {
+		logger.Info("basicAuth", "username", u)
+		ctx = WithBasicAuth(ctx, u, p)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	calls := make(map[string]wsCall)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	finish := func(id json.RawMessage) {
+		mu.Lock()
+		if call, ok := calls[string(id)]; ok {
+			call.cancel(nil)
+			delete(calls, string(id))
+		}
+		mu.Unlock()
+	}
+	write := func(resp wsResponse) {
+		if err := wsjson.Write(ctx, conn, resp); err != nil && ctx.Err() == nil {
+			logger.Error("write", "id", resp.ID, "error", err)
+		}
+	}
+	writeError := func(id json.RawMessage, err error) {
+		logger.Error("call", "id", id, "error", err)
+		eb := NewErrorBody(err)
+		write(wsResponse{ID: id, Error: &eb})
+	}
+	// fail cancels the call with the error, which is written by its receiving goroutine.
+	fail := func(id json.RawMessage, err error) {
+		mu.Lock()
+		call, ok := calls[string(id)]
+		mu.Unlock()
+		if !ok {
+			writeError(id, err)
+			return
+		}
+		call.cancel(err)
+		_ = call.CloseSend()
+	}
+
+	for {
+		var req wsRequest
+		if err := wsjson.Read(ctx, conn, &req); err != nil {
+			if s := websocket.CloseStatus(err); s != websocket.StatusNormalClosure && s != websocket.StatusGoingAway &&
+				!errors.Is(err, context.Canceled) {
+				logger.Error("read", "error", err)
+			}
+			break
+		}
+		logger.Debug("request", "id", req.ID, "method", req.Method, "close", req.Close, "cancel", req.Cancel)
+
+		mu.Lock()
+		call, ok := calls[string(req.ID)]
+		mu.Unlock()
+		if req.Cancel {
+			finish(req.ID)
+			continue
+		}
+		if !ok {
+			if req.Method == "" {
+				writeError(req.ID, fmt.Errorf("no call with id %s: %w", req.ID, ErrNotFound))
+				continue
+			}
+			if h.Input(req.Method) == nil {
+				writeError(req.ID, fmt.Errorf("no unmarshaler for %q: %w", req.Method, ErrNotFound))
+				continue
+			}
+			callCtx, stopTimeout := ctx, context.CancelFunc(func() {})
+			timeout := h.Timeout
+			if timeout == 0 {
+				timeout = DefaultTimeout
+			}
+			if timeout > 0 {
+				callCtx, stopTimeout = context.WithTimeout(ctx, timeout)
+			}
+			callCtx, cancelCall := context.WithCancelCause(callCtx)
+			callCancel := func(err error) { cancelCall(err); stopTimeout() }
+			logger.Info("call", "id", req.ID, "method", req.Method)
+			stream, err := CallStream(h.Client, req.Method, callCtx)
+			if err != nil {
+				callCancel(nil)
+				writeError(req.ID, fmt.Errorf("Call %s: %w", req.Method, err))
+				continue
+			}
+			call = wsCall{Stream: stream, cancel: callCancel, method: req.Method}
+			mu.Lock()
+			calls[string(req.ID)] = call
+			mu.Unlock()
+
+			wg.Add(1)
+			go func(ctx context.Context, id json.RawMessage, recv Receiver) {
+				defer wg.Done()
+				defer finish(id)
+				err := func() error {
+					for part, err := range All(recv) {
+						if err == nil {
+							err = context.Cause(ctx)
+						}
+						if err != nil {
+							return err
+						}
+						write(wsResponse{ID: id, Output: h.JSON.value(part)})
+					}
+					return context.Cause(ctx)
+				}()
+				if err == nil {
+					write(wsResponse{ID: id, End: true})
+					return
+				}
+				if cause := context.Cause(ctx); cause != nil && !errors.Is(err, cause) {
+					err = fmt.Errorf("%w: %w", cause, err)
+				}
+				writeError(id, err)
+			}(callCtx, req.ID, stream)
+		} else if req.Method != "" && req.Method != call.method {
+			writeError(req.ID, fmt.Errorf("call %s is %q, not %q", req.ID, call.method, req.Method))
+			continue
+		}
+
+		if len(req.Input) != 0 {
+			inp := h.Input(call.method)
+			if err := h.JSON.Unmarshal(req.Input, inp); err != nil {
+				fail(req.ID, status.Errorf(codes.InvalidArgument, "decode: %v", err))
+				continue
+			}
+			if err := call.Send(inp); err != nil {
+				fail(req.ID, fmt.Errorf("send: %w", err))
+				continue
+			}
+		}
+		if req.Close {
+			if err := call.CloseSend(); err != nil {
+				logger.Error("closeSend", "id", req.ID, "error", err)
+			}
+		}
+	}
+	// unblock the receiving of the open calls
+	cancel()
+	mu.Lock()
+	for _, call := range calls {
+		_ = call.CloseSend()
+	}
+	mu.Unlock()
+	conn.Close(websocket.StatusNormalClosure, "")
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 Tamás Gulácsi
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcer_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/grpcer"
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	srv := httptest.NewServer(grpcer.WebSocketHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()})
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, strings.Replace(srv.URL, "http", "ws", 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	for _, req := range []string{
+		`{"id":1,"method":"Count","input":{"a":2}}`,
+		`{"id":"s","method":"Sum","input":{"a":1}}`,
+		`{"id":"s","input":{"a":2}}`,
+		`{"id":"s","input":{"a":3},"close":true}`,
+		`{"id":2,"method":"Unknown","input":{}}`,
+	} {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(req)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := make(map[string][]string)
+	for ended := 0; ended < 3; {
+		var resp struct {
+			ID     json.RawMessage
+			Output json.RawMessage
+			Error  *grpcer.ErrorBody
+			End    bool
+		}
+		if err := wsjson.Read(ctx, conn, &resp); err != nil {
+			t.Fatal(err)
+		}
+		id := string(resp.ID)
+		switch {
+		case resp.End:
+			got[id] = append(got[id], "end")
+			ended++
+		case resp.Error != nil:
+			got[id] = append(got[id], "error")
+			ended++
+		default:
+			got[id] = append(got[id], string(resp.Output))
+		}
+	}
+	for id, want := range map[string]string{
+		`1`:   `{"Sum":0,"Count":1} {"Sum":1,"Count":2} end`,
+		`"s"`: `{"Sum":6,"Count":3} end`,
+		`2`:   `error`,
+	} {
+		if s := strings.Join(got[id], " "); s != want {
+			t.Errorf("%s: got %q, wanted %q", id, s, want)
+		}
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func TestWebSocketHandlerDisconnect(t *testing.T) {
+	handled := make(chan struct{})
+	h := grpcer.WebSocketHandler{Client: testClient{}, Logger: zlog.NewT(t).SLog()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(handled)
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, strings.Replace(srv.URL, "http", "ws", 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	for _, req := range []string{
+		`{"id":1,"method":"Count"}`,
+		`{"id":2,"method":"Sum"}`,
+		`{"id":3,"method":"Sum"}`,
+		`{"id":3,"input":[1]}`,
+	} {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(req)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// one error for the bad input
+	var resp struct {
+		ID    json.RawMessage
+		Error *grpcer.ErrorBody
+	}
+	if err := wsjson.Read(ctx, conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.ID) != "3" || resp.Error == nil || resp.Error.Code != "InvalidArgument" {
+		t.Errorf("got %s: %+v, wanted InvalidArgument error for 3", resp.ID, resp.Error)
+	}
+	rctx, rcancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	if err := wsjson.Read(rctx, conn, &resp); err == nil {
+		t.Errorf("got another response: %s: %+v", resp.ID, resp.Error)
+	}
+	rcancel()
+
+	conn.CloseNow()
+	select {
+	case <-handled:
+	case <-ctx.Done():
+		t.Fatal("handler does not return after disconnect")
+	}
+}
@@ -1,16 +1,20 @@
-// Copyright 2017, 2022 Tamás Gulácsi
+// Copyright 2017, 2026 Tamás Gulácsi
 //
 // SPDX-License-Identifier: Apache-2.0
 
 package grpcer
 
 import (
+	"bufio"
 	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"path"
+	"strconv"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -42,44 +46,41 @@ func (h XMLRPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h XMLRPCHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := h.getLogger(ctx)
-	name, params, err := xmlrpc.Unmarshal(r.Body)
-	logger.Info("unmarshal", "name", name, "params", params, "error", err)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("ERROR unmarshaling: %v", err), http.StatusBadRequest)
-		return
+	// XML-RPC, unless another registered codec is asked for
+	var codec Codec = XMLRPCCodec{}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if c := LookupCodec(ct); c != nil {
+			codec = c
+		}
+	}
+	dec := codec.NewDecoder(r.Body)
+	name := path.Base(r.URL.Path)
+	if md, ok := dec.(MethodDecoder); ok {
+		var err error
+		if name, err = md.Method(); err != nil {
+			logger.Error("unmarshal", "error", err)
+			http.Error(w, fmt.Sprintf("ERROR unmarshaling: %v", err), http.StatusBadRequest)
+			return
+		}
 	}
 	inp := h.Input(name)
 	if inp == nil {
 		http.Error(w, fmt.Sprintf("No unmarshaler for %q.", name), http.StatusNotFound)
 		return
 	}
-
-	if len(params) != 1 {
-		http.Error(w, fmt.Sprintf("Wanted 1 struct param, got %d.", len(params)), http.StatusBadRequest)
+	if err := dec.Decode(inp); err != nil {
+		logger.Error("decode", "name", name, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	m, ok := params[0].(map[string]any)
-	if !ok {
-		http.Error(w, fmt.Sprintf("Wanted struct, got %T", params[0]), http.StatusBadRequest)
+	if err := dec.Decode(h.Input(name)); !errors.Is(err, io.EOF) {
+		http.Error(w, fmt.Sprintf("Wanted 1 param: %v", err), http.StatusBadRequest)
 		return
 	}
-
-	// mapstruct
-	for k, v := range m {
-		if s, ok := v.(string); ok && s == "" {
-			delete(m, k)
-			continue
-		}
-		f, _ := utf8.DecodeRune([]byte(k))
-		if unicode.IsLower(f) {
-			m[CamelCase(k)] = v
-		}
-	}
-	if err := mapstructure.WeakDecode(m, inp); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	logger.Info("decoded", "name", name, "inp", inp)
+	if c := acceptCodec(r.Header.Get("Accept"), LookupCodec); c != nil {
+		codec = c
 	}
-	logger.Info("decoded", "inp", inp)
 
 	if u, p, ok := r.BasicAuth(); ok {
 		ctx = WithBasicAuth(ctx, u, p)
@@ -97,37 +98,175 @@ func (h XMLRPCHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	recv, err := h.Call(name, ctx, inp)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.Error("call", "name", name, "error", err)
+		faultError(w, codec, fmt.Errorf("Call %s: %w", name, err))
 		return
 	}
 	part, err := recv.Recv()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.Error("recv", "error", err)
+		faultError(w, codec, fmt.Errorf("recv: %w", err))
 		return
 	}
-	parts := []any{nil}[:0]
-	for {
-		parts = append(parts, part)
-		if part, err = recv.Recv(); err != nil {
-			if !errors.Is(err, io.EOF) {
-				logger.Error("recv", "error", err)
-				parts = parts[:1]
-				parts[0] = xmlrpc.Fault{Code: 111, Message: err.Error()}
-			}
-			break
+	parts := []any{part}
+	for part, err := range All(recv) {
+		if err != nil {
+			logger.Error("recv", "error", err)
+			faultError(w, codec, fmt.Errorf("recv: %w", err))
+			return
 		}
+		parts = append(parts, part)
 	}
 
-	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Type", codec.ContentType())
 	w.WriteHeader(200)
-	if len(parts) == 1 {
-		err = xmlrpc.Marshal(w, "", parts[0])
-	} else {
-		err = xmlrpc.Marshal(w, "", parts)
+	enc := codec.NewEncoder(w)
+	for _, part := range parts {
+		if err = enc.Encode(part); err != nil {
+			break
+		}
+	}
+	if closeErr := closeEncoder(enc); err == nil {
+		err = closeErr
 	}
 	if err != nil {
 		logger.Error("marshal", "error", err)
 	}
 }
 
+// faultError writes the error encoded with the codec:
+// as a fault with 200 OK for XML-RPC, with the status code of the error for others.
+func faultError(w http.ResponseWriter, codec Codec, err error) {
+	code := http.StatusOK
+	if _, ok := codec.(XMLRPCCodec); !ok {
+		code = StatusCodeFromError(err)
+	}
+	codecError(w, codec, err, code)
+}
+
+// XMLRPCCodec is the Codec of XML-RPC.
+//
+// Its Decoder is a MethodDecoder, decoding the struct params of the methodCall one after the other;
+// its Encoder writes the methodResponse when closed: the part, or the array of the parts if more than one,
+// or the fault of the encoded error.
+type XMLRPCCodec struct{}
+
+var _ Codec = XMLRPCCodec{}
+
+func (XMLRPCCodec) ContentType() string            { return "application/xml" }
+func (XMLRPCCodec) NewDecoder(r io.Reader) Decoder { return &xmlrpcDecoder{r: r} }
+func (XMLRPCCodec) NewEncoder(w io.Writer) Encoder { return &xmlrpcEncoder{w: w} }
+
+type xmlrpcDecoder struct {
+	r      io.Reader
+	err    error
+	name   string
+	params []any
+	read   bool
+}
+
+func (d *xmlrpcDecoder) Method() (string, error) {
+	if !d.read {
+		d.read = true
+		d.name, d.params, d.err = xmlrpc.Unmarshal(d.r)
+	}
+	return d.name, d.err
+}
+
+func (d *xmlrpcDecoder) Decode(v any) error {
+	if _, err := d.Method(); err != nil {
+		return err
+	}
+	if len(d.params) == 0 {
+		return io.EOF
+	}
+	p := d.params[0]
+	d.params = d.params[1:]
+	switch m := p.(type) {
+	case xmlrpc.Struct:
+		return xmlrpcDecode(m, v)
+	case map[string]any:
+		return xmlrpcDecode(m, v)
+	}
+	return fmt.Errorf("Wanted struct, got %T", p)
+}
+
+// xmlrpcDecode decodes the struct param into v by mapstructure.WeakDecode,
+// skipping the empty strings, and adding the CamelCase key of the lowercase ones.
+//
+// Unlike weakDecode, it keeps the lowercase keys, and decodes without its hooks.
+func xmlrpcDecode(m map[string]any, v any) error {
+	for k, val := range m {
+		if s, ok := val.(string); ok && s == "" {
+			delete(m, k)
+			continue
+		}
+		f, _ := utf8.DecodeRuneInString(k)
+		if unicode.IsLower(f) {
+			m[CamelCase(k)] = val
+		}
+	}
+	return mapstructure.WeakDecode(m, v)
+}
+
+type xmlrpcEncoder struct {
+	w     io.Writer
+	err   error
+	parts []any
+}
+
+func (e *xmlrpcEncoder) Encode(v any) error { e.parts = append(e.parts, v); return nil }
+func (e *xmlrpcEncoder) EncodeError(err error) error {
+	e.err = err
+	return nil
+}
+func (e *xmlrpcEncoder) Close() error {
+	parts, err := e.parts, e.err
+	e.parts, e.err = nil, nil
+	if err != nil {
+		return encodeXMLRPCFault(e.w, err)
+	}
+	if len(parts) == 1 {
+		return xmlrpc.Marshal(e.w, "", parts[0])
+	}
+	return xmlrpc.Marshal(e.w, "", parts)
+}
+
+// encodeXMLRPCFault writes the error as an XML-RPC fault methodResponse.
+//
+// The faultCode is the gRPC status code (111 for non-gRPC errors), the faultString is the error;
+// the fault struct has a grpcCode (name of the status code), grpcMessage
+// and details (the status details rendered with protojson) member, too.
+func encodeXMLRPCFault(w io.Writer, err error) error {
+	eb := NewErrorBody(err)
+	code := 111
+	if st, ok := grpcStatus(err); ok {
+		code = int(st.Code())
+	}
+	bw := bufio.NewWriter(w)
+	member := func(name, typ, value string) {
+		bw.WriteString("<member><name>" + name + "</name><value><" + typ + ">")
+		xml.EscapeText(bw, []byte(value))
+		bw.WriteString("</" + typ + "></value></member>")
+	}
+	bw.WriteString(`<?xml version="1.0"?><methodResponse><fault><value><struct>`)
+	member("faultCode", "int", strconv.Itoa(code))
+	member("faultString", "string", eb.Error)
+	if eb.Code != "" {
+		member("grpcCode", "string", eb.Code)
+		member("grpcMessage", "string", eb.Message)
+	}
+	if len(eb.Details) != 0 {
+		bw.WriteString("<member><name>details</name><value><array><data>")
+		for _, d := range eb.Details {
+			bw.WriteString("<value><string>")
+			xml.EscapeText(bw, d)
+			bw.WriteString("</string></value>")
+		}
+		bw.WriteString("</data></array></value></member>")
+	}
+	bw.WriteString("</struct></value></fault></methodResponse>")
+	return bw.Flush()
+}
+
 // vim: set fileencoding=utf-8 noet: